@@ -62,6 +62,69 @@ type AppConfig struct {
 	//
 	// `SubdirCache` doesn't have an effect if `Subdir` is empty.
 	SubdirCache string
+
+	// To prevent potential conflicts arising from the same directory being
+	// returned by the `ConfigDir`, `StateDir`, `CacheDir`, and `DataDir` methods, this variable can be used.
+	//
+	// If `SubdirData` is non-empty, and data path is the same path as one of the other paths,
+	// `SubdirData` is appended at the end of data path to prevent overlap of files.
+	//
+	// `SubdirData` doesn't have an effect if `Subdir` is empty.
+	SubdirData string
+
+	// If true, /usr/local/share is used instead of /usr/share as the system-wide
+	// data directory on Unix. Use this if your application is not installed by
+	// the system's package manager.
+	//
+	// This doesn't have an effect on other systems and on Termux.
+	UsrLocal bool
+
+	// Inside strictly confined snaps, $HOME points to a directory that is
+	// specific to the revision of the snap, and files inside it are not
+	// carried over to new revisions in every case.
+	//
+	// If true, and running inside a snap, config, state, and cache directories
+	// are inside $SNAP_USER_COMMON ($SNAP_COMMON if system-wide) instead, which
+	// is kept across refreshes. XDG environment variables are ignored for
+	// them, since they point inside the revision-specific directory too.
+	//
+	// This doesn't have an effect on other systems.
+	SnapCommon bool
+
+	// XDG environment variables (e.g. $XDG_CONFIG_HOME) must contain absolute
+	// paths. Values that are not absolute are ignored, as XDG Base Directory
+	// Specification requires. A value starting with `~` (e.g. `~/.config`) is
+	// ignored too, since it is not expanded by shells when quoted.
+	//
+	// If true, a leading `~` is expanded to the home directory instead.
+	ExpandTilde bool
+
+	// If true, instead of ignoring an invalid XDG environment variable,
+	// `RetrieveAppDirs` returns an error of type `*InvalidEnvError` that
+	// describes why the value was rejected.
+	//
+	// To see which variables are rejected without failing, use `ValidateXDGEnv`.
+	StrictEnv bool
+
+	// If non-empty, environment variables `<EnvPrefix>_CONFIG_DIR`,
+	// `<EnvPrefix>_STATE_DIR`, `<EnvPrefix>_CACHE_DIR`, `<EnvPrefix>_DATA_DIR`,
+	// and `<EnvPrefix>_RUNTIME_DIR` are checked first. If one of them is set,
+	// it is used as is (application directory is not appended) instead of
+	// the platform default.
+	//
+	// Example: if `EnvPrefix` is "MYAPP", $MYAPP_CONFIG_DIR overrides `ConfigDir`.
+	EnvPrefix string
+	// Names of environment variables that override directories of given kinds.
+	// Takes precedence over `EnvPrefix`.
+	//
+	// Example: map[DirKind]string{KindCache: "MYAPP_CACHE"}
+	EnvVars map[DirKind]string
+	// If true, platform defaults are not used at all. Every directory must be
+	// set through an environment variable (see `EnvPrefix` and `EnvVars`),
+	// otherwise `*MissingEnvError` listing the missing ones is returned.
+	//
+	// Useful for deployments that are configured through the environment.
+	RequireEnv bool
 }
 
 type AppDirs struct {
@@ -86,148 +149,291 @@ type AppDirs struct {
 	// are going to be installed, downloaded videos that are going to be
 	// converted to audio format and deleted afterwards.
 	CacheDir string
+	// For files that the application ships with or downloads, and
+	// that are not meant to be edited by the user.
+	//
+	// Example contents: icons, plugins, downloaded assets.
+	DataDir string
+	// For sockets, named pipes, PID files, and locks. Files inside this
+	// directory must not survive a reboot.
+	//
+	// On Unix, if $XDG_RUNTIME_DIR is unset, a directory inside the
+	// temporary directory is used instead. If it exists, but it is not a
+	// directory that only the user can access, `RuntimeDir` is empty. It is
+	// not created; call `EnsureRuntimeDir` before using it.
+	RuntimeDir string
+
+	// Whether the directories are system-wide directories.
+	SystemWide bool
 }
 
+// RetrieveAppDirs retrieves application directories from the environment
+// of the current process. `config` can be nil.
 func RetrieveAppDirs(systemWide bool, config *AppConfig) (appDirs *AppDirs, err error) {
-	if config == nil {
-		config = new(AppConfig)
+	return defaultResolver.AppDirs(systemWide, config)
+}
+
+// AppDirs is the same as `RetrieveAppDirs`, but it uses the environment of
+// the resolver.
+func (r *Resolver) AppDirs(systemWide bool, config *AppConfig) (appDirs *AppDirs, err error) {
+	if r.memo != nil {
+		if config == nil {
+			config = new(AppConfig)
+		}
+		v, err := r.memoized(memoKey("AppDirs", systemWide, *config), func(r *Resolver) (any, error) {
+			return r.AppDirs(systemWide, config)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Copied, so that the memoized value cannot be modified.
+		appDirs := *v.(*AppDirs)
+		return &appDirs, nil
 	}
-	appDirs = new(AppDirs)
-	appDirs.ConfigDir, err = config.configDir(systemWide)
-	if err != nil {
-		err = fmt.Errorf("finddirs: %w", err)
-		return
+
+	l := r.newLookup(config, nil)
+	appDirs = &AppDirs{SystemWide: systemWide}
+	var missing []DirKind
+	for _, kind := range dirKinds {
+		dir, ok, err := l.resolveDir(kind, systemWide)
+		if err != nil {
+			return nil, fmt.Errorf("finddirs: %w", err)
+		}
+		if !ok {
+			missing = append(missing, kind)
+			continue
+		}
+		*appDirs.dir(kind) = dir
 	}
-	appDirs.StateDir, err = config.stateDir(systemWide)
-	if err != nil {
-		err = fmt.Errorf("finddirs: %w", err)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("finddirs: %w", l.config.missingEnvError(missing))
+	}
+	return
+}
+
+// resolveDir returns the directory of `kind` as `AppDirs` does: the one set
+// through an environment variable takes precedence over the platform
+// default. If it is not set, and `RequireEnv` is set, `ok` is false.
+func (l *lookup) resolveDir(kind DirKind, systemWide bool) (dir string, ok bool, err error) {
+	dir, ok, err = l.envOverride(kind)
+	if err != nil || ok || l.config.RequireEnv {
 		return
 	}
-	appDirs.CacheDir, err = config.cacheDir(systemWide)
+	dir, err = l.dir(kind, systemWide)
+	return dir, err == nil, err
+}
+
+// Dir returns the directory of given kind.
+func (d *AppDirs) Dir(kind DirKind) string { return *d.dir(kind) }
+
+func (d *AppDirs) dir(kind DirKind) *string {
+	switch kind {
+	case KindConfig:
+		return &d.ConfigDir
+	case KindState:
+		return &d.StateDir
+	case KindCache:
+		return &d.CacheDir
+	case KindData:
+		return &d.DataDir
+	case KindRuntime:
+		return &d.RuntimeDir
+	}
+	panic("finddirs: unknown directory kind")
+}
+
+// xdgEnv returns the validated value of the XDG environment variable `name`.
+// If it is invalid, empty string is returned, unless `StrictEnv` is set.
+func (l *lookup) xdgEnv(name string) (string, error) {
+	dir, invalid, err := envDir(name, l.env.Getenv(name), l.config.ExpandTilde, l.env.HomeDir)
 	if err != nil {
-		err = fmt.Errorf("finddirs: %w", err)
-		return
+		return "", err
 	}
-	return
+	if invalid != nil && l.config.StrictEnv {
+		return "", invalid
+	}
+	return dir, nil
 }
 
-func (c *AppConfig) subdir() string {
-	subdir := c.subdirPlatformSpecific()
+func (l *lookup) subdir() string {
+	subdir := l.subdirPlatformSpecific()
 	if subdir != "" {
 		return subdir
 	}
-	return c.Subdir
+	return l.config.Subdir
 }
 
-func (c *AppConfig) configDir(systemWide bool) (configDir string, err error) {
+func (l *lookup) configDir(systemWide bool) (configDir string, err error) {
 	if systemWide {
-		configDir, err = c.configDirSystem()
+		configDir, err = l.configDirSystem()
 	} else {
-		configDir, err = c.configDirLocal()
+		configDir, err = l.configDirLocal()
 	}
 	if err != nil {
 		return
 	}
 
-	if !(c.NoEtcSubdir && strings.HasSuffix(configDir, "/etc")) {
-		subdir := c.subdir()
-		if len(subdir) > 0 {
-			configDir = path.Join(configDir, subdir)
-		}
+	return filepath.ToSlash(l.appendConfigSubdir(configDir)), nil
+}
+
+func (l *lookup) appendSubdir(dir string) string {
+	subdir := l.subdir()
+	if len(subdir) > 0 {
+		return path.Join(dir, subdir)
+	}
+	return dir
+}
+
+// Same as `appendSubdir`, but takes `NoEtcSubdir` into account.
+func (l *lookup) appendConfigSubdir(dir string) string {
+	if l.config.NoEtcSubdir && strings.HasSuffix(dir, "/etc") {
+		return dir
 	}
-	return filepath.ToSlash(configDir), nil
+	return l.appendSubdir(dir)
 }
 
-func (c *AppConfig) stateDir(systemWide bool) (stateDir string, err error) {
+// DirKind identifies a directory inside `AppDirs`.
+type DirKind int
+
+const (
+	KindConfig DirKind = iota
+	KindState
+	KindCache
+	KindData
+	KindRuntime
+)
+
+var dirKinds = []DirKind{KindConfig, KindState, KindCache, KindData, KindRuntime}
+
+func (k DirKind) String() string {
+	switch k {
+	case KindConfig:
+		return "config"
+	case KindState:
+		return "state"
+	case KindCache:
+		return "cache"
+	case KindData:
+		return "data"
+	case KindRuntime:
+		return "runtime"
+	}
+	return fmt.Sprintf("DirKind(%d)", int(k))
+}
+
+// dir returns the directory for `kind` with the application directory appended.
+//
+// On Unix, system-wide directories systemd has set up for the service
+// (e.g. $STATE_DIRECTORY) take precedence.
+func (l *lookup) dir(kind DirKind, systemWide bool) (string, error) {
 	if systemWide {
-		stateDir, err = c.stateDirSystem()
-	} else {
-		stateDir, err = c.stateDirLocal()
+		if dir, ok := l.systemdDir(kind); ok {
+			return dir, nil
+		}
+	}
+	switch kind {
+	case KindConfig:
+		return l.configDir(systemWide)
+	case KindState:
+		return l.stateDir(systemWide)
+	case KindCache:
+		return l.cacheDir(systemWide)
+	case KindData:
+		return l.dataDir(systemWide)
+	case KindRuntime:
+		return l.runtimeDir(systemWide)
 	}
+	panic("finddirs: unknown directory kind")
+}
+
+// baseDir returns the directory for `kind` without the application directory appended.
+func (l *lookup) baseDir(kind DirKind, systemWide bool) (string, error) {
+	switch kind {
+	case KindConfig:
+		if systemWide {
+			return l.configDirSystem()
+		}
+		return l.configDirLocal()
+	case KindState:
+		if systemWide {
+			return l.stateDirSystem()
+		}
+		return l.stateDirLocal()
+	case KindCache:
+		if systemWide {
+			return l.cacheDirSystem()
+		}
+		return l.cacheDirLocal()
+	case KindData:
+		if systemWide {
+			return l.dataDirSystem()
+		}
+		return l.dataDirLocal()
+	}
+	panic("finddirs: unknown directory kind")
+}
+
+// appDir returns the directory for `kind` with the application directory appended.
+//
+// If `collisionSubdir` is non-empty, and the returned path would be the same path
+// as the one of other kinds, `collisionSubdir` is appended at the end of it.
+func (l *lookup) appDir(kind DirKind, systemWide bool, collisionSubdir string) (dir string, err error) {
+	dir, err = l.baseDir(kind, systemWide)
 	if err != nil {
 		return
 	}
 
-	subdir := c.subdir()
+	subdir := l.subdir()
 	if len(subdir) > 0 {
-		stateDir = path.Join(stateDir, subdir)
-
-		// Append `SubdirState` if necessary
-		if c.SubdirState != "" {
-			var (
-				configDir string
-				cacheDir  string
-			)
-			if systemWide {
-				configDir, err = c.configDirSystem()
-				if err != nil {
-					return "", err
-				}
-				cacheDir, err = c.cacheDirSystem()
-				if err != nil {
-					return "", err
+		dir = path.Join(dir, subdir)
+
+		// Append `collisionSubdir` if necessary
+		if collisionSubdir != "" {
+			for _, other := range dirKinds {
+				// Runtime directory is not checked, since it is
+				// never the same directory as the others.
+				if other == kind || other == KindRuntime {
+					continue
 				}
-			} else {
-				configDir, err = c.configDirLocal()
+				otherDir, err := l.baseDir(other, systemWide)
 				if err != nil {
 					return "", err
 				}
-				cacheDir, err = c.cacheDirLocal()
-				if err != nil {
-					return "", err
+				if dir == path.Join(otherDir, subdir) {
+					dir = path.Join(dir, collisionSubdir)
+					break
 				}
 			}
-			if stateDir == path.Join(configDir, subdir) || stateDir == path.Join(cacheDir, subdir) {
-				stateDir = path.Join(stateDir, c.SubdirState)
-			}
 		}
 	}
-	return filepath.ToSlash(stateDir), nil
+	return filepath.ToSlash(dir), nil
+}
+
+func (l *lookup) stateDir(systemWide bool) (string, error) {
+	return l.appDir(KindState, systemWide, l.config.SubdirState)
+}
+
+func (l *lookup) cacheDir(systemWide bool) (string, error) {
+	return l.appDir(KindCache, systemWide, l.config.SubdirCache)
 }
 
-func (c *AppConfig) cacheDir(systemWide bool) (cacheDir string, err error) {
+func (l *lookup) dataDir(systemWide bool) (string, error) {
+	return l.appDir(KindData, systemWide, l.config.SubdirData)
+}
+
+func (l *lookup) runtimeDir(systemWide bool) (runtimeDir string, err error) {
 	if systemWide {
-		cacheDir, err = c.cacheDirSystem()
+		runtimeDir, err = l.runtimeDirSystem()
 	} else {
-		cacheDir, err = c.cacheDirLocal()
+		runtimeDir, err = l.runtimeDirLocal()
 	}
-	if err != nil {
+	if err != nil || runtimeDir == "" {
 		return
 	}
 
-	subdir := c.subdir()
+	subdir := l.subdir()
 	if len(subdir) > 0 {
-		cacheDir = path.Join(cacheDir, subdir)
-
-		// Append `SubdirCache` if necessary
-		if c.SubdirCache != "" {
-			var (
-				configDir string
-				stateDir  string
-			)
-			if systemWide {
-				configDir, err = c.configDirSystem()
-				if err != nil {
-					return "", err
-				}
-				stateDir, err = c.stateDirSystem()
-				if err != nil {
-					return "", err
-				}
-			} else {
-				configDir, err = c.configDirLocal()
-				if err != nil {
-					return "", err
-				}
-				stateDir, err = c.stateDirLocal()
-				if err != nil {
-					return "", err
-				}
-			}
-			if cacheDir == path.Join(stateDir, subdir) || cacheDir == path.Join(configDir, subdir) {
-				cacheDir = path.Join(cacheDir, c.SubdirCache)
-			}
-		}
+		runtimeDir = path.Join(runtimeDir, subdir)
 	}
-	return filepath.ToSlash(cacheDir), nil
+	return filepath.ToSlash(runtimeDir), nil
 }
@@ -0,0 +1,69 @@
+package finddirs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+func (OSEnv) WriteFile(name string, data []byte, perm os.FileMode) error {
+	name = filepath.FromSlash(name)
+	err := os.MkdirAll(filepath.Dir(name), 0o700)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(name, data, perm)
+}
+
+// writeFileAtomic writes `data` to a temporary file inside the same
+// directory as `filePath`, syncs it, and renames it to `filePath`. Either
+// the old or the new contents are seen, even if the process crashes.
+func writeFileAtomic(filePath string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(filePath)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	_, err = f.Write(data)
+	if err != nil {
+		return err
+	}
+	err = f.Chmod(perm)
+	if err != nil && runtime.GOOS != "windows" && runtime.GOOS != "plan9" {
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(f.Name(), filePath)
+	if err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir syncs the directory so that a rename inside it is persisted.
+// Directories cannot be synced on Windows, hence this is a no-op there.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,184 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// AuditProblem is a problem `Audit` reports.
+type AuditProblem int
+
+const (
+	// Directory is writable by group or others. Parent directories with
+	// the sticky bit set (e.g. /tmp) are not reported.
+	ProblemWritable AuditProblem = iota
+	// Directory is owned by a user other than the current user and root.
+	ProblemForeignOwner
+	// Path is a symbolic link that points to a file that is writable by
+	// group or others, or owned by a user other than the current user and root.
+	ProblemUnsafeSymlink
+)
+
+func (p AuditProblem) String() string {
+	switch p {
+	case ProblemWritable:
+		return "writable by group or others"
+	case ProblemForeignOwner:
+		return "owned by another user"
+	case ProblemUnsafeSymlink:
+		return "symbolic link to an unsafe location"
+	}
+	return fmt.Sprintf("AuditProblem(%d)", int(p))
+}
+
+type AuditIssue struct {
+	// Kind of the directory the issue affects.
+	Kind DirKind
+	// Path that has the problem. Either the directory itself, or one of its parents.
+	Path    string
+	Problem AuditProblem
+	// Mode of `Path` (before it is fixed).
+	Mode fs.FileMode
+	// Whether the problem is fixed (see `AuditConfig.Fix`).
+	Fixed bool
+}
+
+func (i *AuditIssue) String() string {
+	s := fmt.Sprintf("%s directory: %s is %s (mode %s)", i.Kind, i.Path, i.Problem, i.Mode)
+	if i.Fixed {
+		s += ", fixed"
+	}
+	return s
+}
+
+type AuditConfig struct {
+	// Directories that are trusted. Parents are checked up to (and excluding)
+	// these directories. If empty, parents are checked up to the root directory.
+	//
+	// Example: []string{"/home"}
+	TrustedRoots []string
+
+	// If true, group and others write permissions are removed from the directories
+	// inside `AppDirs` that are owned by the current user. Parent directories
+	// are never changed, and foreign owners and symbolic links are not fixed.
+	Fix bool
+}
+
+// Audit checks each directory inside `d` and each of its parents, and
+// returns the problems found. If one of them is a symbolic link, the
+// parents of the directory it points to are checked too. Directories that
+// don't exist are skipped. `config` can be nil.
+//
+// Only supported on Unix. On other systems, `ErrOSNotSupportedAudit` is returned.
+func (d *AppDirs) Audit(config *AuditConfig) (issues []AuditIssue, err error) {
+	if !ownersSupported {
+		return nil, fmt.Errorf("finddirs: %w", ErrOSNotSupportedAudit)
+	}
+	if config == nil {
+		config = new(AuditConfig)
+	}
+	trusted := make(map[string]bool, len(config.TrustedRoots))
+	for _, root := range config.TrustedRoots {
+		trusted[path.Clean(filepath.ToSlash(root))] = true
+	}
+
+	// Parents are shared between directories. Report them once.
+	seen := make(map[string]bool)
+	for _, kind := range dirKinds {
+		dir := d.Dir(kind)
+		if dir == "" {
+			continue
+		}
+		appDir := path.Clean(filepath.ToSlash(dir))
+		// Parents of the directories symbolic links point to are checked
+		// too, since they can be changed just like the ones of `dir`.
+		starts := []string{appDir}
+		for len(starts) > 0 {
+			for p := starts[0]; !trusted[p] && !seen[p]; p = path.Dir(p) {
+				seen[p] = true
+				var target string
+				issues, target, err = auditPath(issues, kind, p, p == appDir, config.Fix)
+				if err != nil {
+					return nil, fmt.Errorf("finddirs: %w", err)
+				}
+				if target != "" {
+					starts = append(starts, path.Dir(target))
+				}
+				if p == path.Dir(p) {
+					break
+				}
+			}
+			starts = starts[1:]
+		}
+	}
+	return
+}
+
+// auditPath appends the problems of `p` to `issues`. If `p` is a symbolic
+// link, the path it points to (with every symbolic link resolved) is
+// returned as `target`.
+func auditPath(issues []AuditIssue, kind DirKind, p string, isAppDir, fix bool) (_ []AuditIssue, target string, err error) {
+	name := filepath.FromSlash(p)
+	fi, err := os.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return issues, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		targetInfo, err := os.Stat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return issues, "", nil
+		} else if err != nil {
+			return nil, "", err
+		}
+		if isWritableByOthers(targetInfo, isAppDir) || isForeign(targetInfo) {
+			issues = append(issues, AuditIssue{Kind: kind, Path: p, Problem: ProblemUnsafeSymlink, Mode: targetInfo.Mode()})
+		}
+		target, err = filepath.EvalSymlinks(name)
+		if err != nil {
+			return nil, "", err
+		}
+		return issues, filepath.ToSlash(target), nil
+	}
+
+	if isForeign(fi) {
+		issues = append(issues, AuditIssue{Kind: kind, Path: p, Problem: ProblemForeignOwner, Mode: fi.Mode()})
+	}
+	if isWritableByOthers(fi, isAppDir) {
+		issue := AuditIssue{Kind: kind, Path: p, Problem: ProblemWritable, Mode: fi.Mode()}
+		if uid, _ := fileOwner(fi); fix && isAppDir && uid == os.Getuid() {
+			// Perm() would clear the setgid and sticky bits.
+			mode := fi.Mode() & (fs.ModePerm | fs.ModeSetgid | fs.ModeSticky)
+			err = os.Chmod(name, mode&^0o022)
+			if err != nil {
+				return nil, "", err
+			}
+			issue.Fixed = true
+		}
+		issues = append(issues, issue)
+	}
+	return issues, "", nil
+}
+
+// isWritableByOthers reports whether the file is writable by group or others.
+// Unless `strict` is true, directories with the sticky bit set are not
+// reported, since others cannot remove or rename files inside them.
+func isWritableByOthers(fi fs.FileInfo, strict bool) bool {
+	if !strict && fi.IsDir() && fi.Mode()&fs.ModeSticky != 0 {
+		return false
+	}
+	return fi.Mode().Perm()&0o022 != 0
+}
+
+// isForeign reports whether the file is owned by a user other than the
+// current user and root.
+func isForeign(fi fs.FileInfo) bool {
+	uid, ok := fileOwner(fi)
+	return ok && uid != os.Getuid() && uid != 0
+}
@@ -0,0 +1,120 @@
+package finddirs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// RetrieveBinDir returns the directory for user-level (or system-wide if
+// `systemWide` is true) executables. Installers should put binaries into it.
+//
+// Application directory is not appended to the returned path, since the
+// directory is meant to be on $PATH. Use `IsInPath` to check whether it is.
+func RetrieveBinDir(systemWide bool) (dir string, err error) {
+	return RetrieveBinDirWithConfig(systemWide, nil)
+}
+
+// RetrieveBinDirWithConfig is the same as `RetrieveBinDir`, but $XDG_BIN_HOME
+// is validated as `ExpandTilde` and `StrictEnv` in `config` require. Other
+// fields of `config` are not used. `config` can be nil.
+func RetrieveBinDirWithConfig(systemWide bool, config *AppConfig) (dir string, err error) {
+	return defaultResolver.BinDir(systemWide, config)
+}
+
+// BinDir is the same as `RetrieveBinDirWithConfig`, but it uses the
+// environment of the resolver.
+func (r *Resolver) BinDir(systemWide bool, config *AppConfig) (dir string, err error) {
+	if r.memo != nil {
+		if config == nil {
+			config = new(AppConfig)
+		}
+		v, err := r.memoized(memoKey("BinDir", systemWide, *config), func(r *Resolver) (any, error) {
+			return r.BinDir(systemWide, config)
+		})
+		if err != nil {
+			return "", err
+		}
+		return v.(string), nil
+	}
+
+	l := r.newLookup(config, nil)
+	if systemWide {
+		dir, err = l.binDirSystem()
+	} else {
+		dir, err = l.binDirLocal()
+	}
+	if err != nil {
+		return "", fmt.Errorf("finddirs: %w", err)
+	}
+	return filepath.ToSlash(dir), nil
+}
+
+// IsInPath reports whether `dir` is one of the directories in $PATH
+// ($path on Plan 9), so that executables inside it can be run by their name.
+func IsInPath(dir string) bool {
+	return defaultResolver.IsInPath(dir)
+}
+
+// IsInPath is the same as the package-level `IsInPath`, but it uses the
+// environment of the resolver.
+func (r *Resolver) IsInPath(dir string) bool {
+	l := r.newLookup(nil, nil)
+	dir = l.cleanPath(dir)
+	dirInfo, dirErr := r.env.Stat(filepath.FromSlash(dir))
+	for _, entry := range splitPathList(r.env.Getenv(l.pathEnvVar()), l.pathListSeparator()) {
+		if entry == "" {
+			continue
+		}
+		entry = l.cleanPath(entry)
+		if entry == dir || (l.caseInsensitivePaths() && strings.EqualFold(entry, dir)) {
+			return true
+		}
+		// Handle symlinks
+		if dirErr == nil {
+			entryInfo, err := r.env.Stat(filepath.FromSlash(entry))
+			if err == nil && os.SameFile(dirInfo, entryInfo) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cleanPath cleans `p` as a path of the platform, and converts it to
+// forward slashes. On Windows, backslashes are separators too, even if we're
+// not running on Windows.
+func (l *lookup) cleanPath(p string) string {
+	if _, ok := l.platform.(windowsPlatform); ok {
+		p = strings.ReplaceAll(p, `\`, "/")
+	}
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// splitPathList splits `list` on `sep`, just like filepath.SplitList does on
+// the system `sep` belongs to. On Windows (where `sep` is ';'), entries can
+// be quoted, so that they can contain the separator.
+func splitPathList(list string, sep byte) []string {
+	if list == "" {
+		return nil
+	}
+	var (
+		entries []string
+		entry   strings.Builder
+		quoted  bool
+	)
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case c == '"' && sep == ';':
+			quoted = !quoted
+		case c == sep && !quoted:
+			entries = append(entries, entry.String())
+			entry.Reset()
+		default:
+			entry.WriteByte(c)
+		}
+	}
+	return append(entries, entry.String())
+}
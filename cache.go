@@ -0,0 +1,358 @@
+package finddirs
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cacheLockFileName = ".lock"
+	cacheEntrySuffix  = ".entry"
+	// Entries start with the expiration time (Unix time in nanoseconds,
+	// 0 if the entry doesn't expire) as a big-endian int64.
+	cacheHeaderSize = 8
+
+	defaultCachePruneInterval = 10 * time.Minute
+)
+
+type CacheConfig struct {
+	// Directory inside `CacheDir` to store entries in.
+	// If empty, entries are stored inside `CacheDir` itself.
+	Subdir string
+
+	// Maximum total size of entries on disk in bytes. When it is exceeded,
+	// least recently used entries are evicted. If 0, size is not limited.
+	MaxSize int64
+
+	// Minimum free space on the filesystem in bytes. When free space falls
+	// below it, least recently used entries are evicted until there is enough
+	// free space, or the cache is empty. If 0, free space is not checked.
+	//
+	// Free space can only be determined on Linux, macOS, FreeBSD,
+	// DragonFly BSD, and Windows. This doesn't have an effect on other systems.
+	MinFreeSpace int64
+
+	// `Put` prunes the cache (see `Prune`) when it exceeds the limits above,
+	// or when the cache hasn't been pruned for `PruneInterval`, so that
+	// expired entries and entries written by other processes are accounted
+	// for. If 0, 10 minutes is used.
+	PruneInterval time.Duration
+}
+
+// Cache is a key-value store inside `CacheDir`. Each entry is a file, and
+// it is written atomically. Entries can expire, and least recently used
+// entries are evicted to stay within the limits in `CacheConfig`.
+//
+// Cache can be shared by goroutines and processes: operations are
+// synchronized with a file lock. File locks are supported on Linux, macOS,
+// iOS, FreeBSD, NetBSD, OpenBSD, DragonFly BSD, and Windows. On other
+// systems, `ErrOSNotSupportedLock` is returned from operations.
+type Cache struct {
+	dir    string
+	config CacheConfig
+
+	mu sync.Mutex
+	// Total size of entries as of the last prune, adjusted by the writes of
+	// this process since then. Writes of other processes are not included.
+	size      int64
+	lastPrune time.Time
+}
+
+// OpenCache creates the cache directory if it doesn't exist, and returns
+// a cache stored inside it. `config` can be nil.
+func OpenCache(dirs *AppDirs, config *CacheConfig) (*Cache, error) {
+	if config == nil {
+		config = new(CacheConfig)
+	}
+	if dirs.CacheDir == "" {
+		return nil, fmt.Errorf("finddirs: cache directory is not set")
+	}
+	dir := dirs.CacheDir
+	if config.Subdir != "" {
+		err := checkFileName(config.Subdir)
+		if err != nil {
+			return nil, fmt.Errorf("finddirs: %w", err)
+		}
+		dir = path.Join(dir, filepath.ToSlash(config.Subdir))
+	}
+	_, err := ensureDir(dir, defaultDirMode(KindCache, dirs.SystemWide))
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	c := &Cache{dir: filepath.FromSlash(dir), config: *config}
+	if c.config.PruneInterval == 0 {
+		c.config.PruneInterval = defaultCachePruneInterval
+	}
+	return c, nil
+}
+
+// Get returns the data of the entry `key`. If there is no such entry,
+// or it is expired, `ok` is false.
+func (c *Cache) Get(key string) (data []byte, ok bool, err error) {
+	unlock, err := c.lock(false)
+	if err != nil {
+		return nil, false, fmt.Errorf("finddirs: %w", err)
+	}
+	defer unlock()
+
+	filePath := c.entryPath(key)
+	data, err = os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("finddirs: %w", err)
+	}
+	if len(data) < cacheHeaderSize || isExpired(data, time.Now()) {
+		// Removed by the next `Prune`.
+		return nil, false, nil
+	}
+
+	// Modification time is used as the last access time, since access
+	// times are often not updated (e.g. with noatime).
+	now := time.Now()
+	err = os.Chtimes(filePath, now, now)
+	if err != nil {
+		return nil, false, fmt.Errorf("finddirs: %w", err)
+	}
+	return data[cacheHeaderSize:], true, nil
+}
+
+// Put stores `data` as the entry `key`, replacing the existing one. The
+// entry expires after `ttl`. If `ttl` is 0, it doesn't expire. If it is
+// negative, an error is returned.
+//
+// Afterwards, the cache is pruned (see `Prune`) if it exceeds the limits in
+// `CacheConfig`, or `PruneInterval` has passed since it was last pruned.
+func (c *Cache) Put(key string, data []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("finddirs: negative TTL: %s", ttl)
+	}
+	unlock, err := c.lock(true)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	defer unlock()
+
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixNano()
+	}
+	entry := make([]byte, cacheHeaderSize, cacheHeaderSize+len(data))
+	binary.BigEndian.PutUint64(entry, uint64(expires))
+	entry = append(entry, data...)
+	filePath := c.entryPath(key)
+	oldSize := fileSize(filePath)
+	err = writeFileAtomic(filePath, entry, 0o600)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size += int64(len(entry)) - oldSize
+	if !c.needsPrune() {
+		return nil
+	}
+	err = c.prune()
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the entry `key`. It is not an error if there is no such entry.
+func (c *Cache) Delete(key string) error {
+	unlock, err := c.lock(true)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	defer unlock()
+
+	filePath := c.entryPath(key)
+	size := fileSize(filePath)
+	err = os.Remove(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	c.mu.Lock()
+	c.size -= size
+	c.mu.Unlock()
+	return nil
+}
+
+// Prune removes expired entries, and evicts least recently used entries
+// until the cache is within the limits in `CacheConfig`.
+func (c *Cache) Prune() error {
+	unlock, err := c.lock(true)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	defer unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err = c.prune()
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	return nil
+}
+
+type cacheEntry struct {
+	path       string
+	size       int64
+	lastAccess time.Time
+}
+
+// needsPrune reports whether the cache might exceed its limits, or it is
+// time to prune it. It must be called with `mu` held.
+func (c *Cache) needsPrune() bool {
+	if time.Since(c.lastPrune) >= c.config.PruneInterval {
+		return true
+	}
+	if c.config.MaxSize > 0 && c.size > c.config.MaxSize {
+		return true
+	}
+	if c.config.MinFreeSpace > 0 {
+		free, ok := freeSpace(c.dir)
+		return ok && free < c.config.MinFreeSpace
+	}
+	return false
+}
+
+// prune must be called with the exclusive lock and `mu` held.
+func (c *Cache) prune() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	var (
+		entries []cacheEntry
+		total   int64
+		now     = time.Now()
+	)
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		filePath := filepath.Join(c.dir, name)
+		if strings.HasPrefix(name, ".") && strings.Contains(name, cacheEntrySuffix+".tmp") {
+			// Temporary file of a write that didn't complete. Since writes
+			// hold the lock, no write is in progress.
+			err = os.Remove(filePath)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(name, cacheEntrySuffix) || !dirEntry.Type().IsRegular() {
+			continue
+		}
+
+		fi, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		expired, err := isEntryExpired(filePath, now)
+		if err != nil {
+			return err
+		}
+		if expired {
+			err = os.Remove(filePath)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		entries = append(entries, cacheEntry{path: filePath, size: fi.Size(), lastAccess: fi.ModTime()})
+		total += fi.Size()
+	}
+
+	// Least recently used first
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastAccess.Before(entries[j].lastAccess) })
+
+	free, checkFree := freeSpace(c.dir)
+	checkFree = checkFree && c.config.MinFreeSpace > 0
+	for _, entry := range entries {
+		overSize := c.config.MaxSize > 0 && total > c.config.MaxSize
+		lowSpace := checkFree && free < c.config.MinFreeSpace
+		if !overSize && !lowSpace {
+			break
+		}
+		err = os.Remove(entry.path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= entry.size
+		free += entry.size
+	}
+	c.size = total
+	c.lastPrune = now
+	return nil
+}
+
+// fileSize returns the size of the file at `filePath`, or 0 if it cannot
+// be stat'ed.
+func fileSize(filePath string) int64 {
+	fi, err := os.Lstat(filePath)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+func (c *Cache) entryPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+cacheEntrySuffix)
+}
+
+// lock locks the lock file of the cache, and returns a function that unlocks it.
+func (c *Cache) lock(exclusive bool) (unlock func(), err error) {
+	f, err := os.OpenFile(filepath.Join(c.dir, cacheLockFileName), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	err = lockFile(f, exclusive, true)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// isEntryExpired reads the header of the entry at `filePath`, and reports
+// whether it is expired. Entries that are too short are reported as expired.
+func isEntryExpired(filePath string, now time.Time) (bool, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	header := make([]byte, cacheHeaderSize)
+	_, err = io.ReadFull(f, header)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return isExpired(header, now), nil
+}
+
+func isExpired(entry []byte, now time.Time) bool {
+	expires := int64(binary.BigEndian.Uint64(entry))
+	return expires != 0 && now.UnixNano() >= expires
+}
@@ -0,0 +1,79 @@
+package finddirs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	d := &AppDirs{CacheDir: filepath.ToSlash(t.TempDir()) + "/foo"}
+	c, err := OpenCache(d, &CacheConfig{Subdir: "http", MaxSize: 3 * (cacheHeaderSize + 10)})
+	require.NoError(t, err)
+	require.DirExists(t, d.CacheDir+"/http")
+
+	err = c.Put("a", []byte("0123456789"), 0)
+	if errors.Is(err, ErrOSNotSupportedLock) {
+		t.Skip(runtime.GOOS, err)
+	}
+	require.NoError(t, err)
+	data, ok, err := c.Get("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "0123456789", string(data))
+
+	_, ok, err = c.Get("b")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Expiration
+	require.NoError(t, c.Put("b", []byte("0123456789"), time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	_, ok, err = c.Get("b")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.NoError(t, c.Prune())
+	require.NoFileExists(t, c.entryPath("b"))
+
+	// Least recently used entry is evicted
+	require.NoError(t, c.Put("b", []byte("0123456789"), 0))
+	require.NoError(t, c.Put("c", []byte("0123456789"), 0))
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(c.entryPath("a"), past, past))
+	require.NoError(t, os.Chtimes(c.entryPath("b"), past.Add(-time.Minute), past.Add(-time.Minute)))
+	require.NoError(t, c.Put("d", []byte("0123456789"), 0))
+	require.NoFileExists(t, c.entryPath("b"))
+	for _, key := range []string{"a", "c", "d"} {
+		_, ok, err = c.Get(key)
+		require.NoError(t, err)
+		require.True(t, ok, key)
+	}
+
+	require.NoError(t, c.Delete("a"))
+	require.NoError(t, c.Delete("a"))
+	_, ok, err = c.Get("a")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.Error(t, c.Put("e", nil, -time.Second))
+
+	// Put doesn't prune within the limits.
+	require.NoError(t, c.Put("e", nil, time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, c.Put("f", nil, 0))
+	require.FileExists(t, c.entryPath("e"))
+	c.lastPrune = time.Now().Add(-c.config.PruneInterval)
+	require.NoError(t, c.Put("f", nil, 0))
+	require.NoFileExists(t, c.entryPath("e"))
+
+	// Leftover temporary files are removed.
+	tmp := filepath.Join(c.dir, "."+filepath.Base(c.entryPath("e"))+".tmp123")
+	require.NoError(t, os.WriteFile(tmp, nil, 0o600))
+	require.NoError(t, c.Prune())
+	require.NoFileExists(t, tmp)
+}
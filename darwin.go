@@ -1,87 +1,88 @@
-//go:build darwin || ios
-
 package finddirs
 
-import (
-	"path"
-	"runtime"
+import "path"
 
-	"github.com/mitchellh/go-homedir"
-)
+// darwinPlatform retrieves directories as macOS and iOS lay them out.
+type darwinPlatform struct {
+	*lookup
+	ios bool
+}
 
-var isIOS = runtime.GOOS == "ios"
+func (l darwinPlatform) setUserDirs(dirs map[string]string) error {
+	return ErrOSNotSupportedSetUserDirs
+}
 
-func desktopDir() (string, error) {
-	if isIOS {
+func (l darwinPlatform) desktopDir() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Desktop"), nil
 }
 
-func downloadsDir() (string, error) {
-	if isIOS {
+func (l darwinPlatform) downloadsDir() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Downloads"), nil
 }
 
-func documentsDir() (string, error) {
-	if isIOS {
+func (l darwinPlatform) documentsDir() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Documents"), nil
 }
 
-func picturesDir() (string, error) {
-	if isIOS {
+func (l darwinPlatform) picturesDir() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Pictures"), nil
 }
 
-func videosDir() (string, error) {
-	if isIOS {
+func (l darwinPlatform) videosDir() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Movies"), nil
 }
 
-func musicDir() (string, error) {
-	if isIOS {
+func (l darwinPlatform) musicDir() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Music"), nil
 }
 
-func fontsDirs() ([]string, error) {
-	if isIOS {
+func (l darwinPlatform) fontsDirs() ([]string, error) {
+	if l.ios {
 		return nil, ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return nil, err
 	}
@@ -94,71 +95,143 @@ func fontsDirs() ([]string, error) {
 	}, nil
 }
 
-func templatesDir() (string, error) {
-	if isIOS {
+func (l darwinPlatform) templatesDir() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Templates"), nil
 }
 
-func publicShareDir() (string, error) {
-	if isIOS {
+func (l darwinPlatform) publicShareDir() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedUserDirs
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Public"), nil
 }
 
-func (c *AppConfig) subdirPlatformSpecific() string { return c.SubdirDarwinIOS }
+func (l darwinPlatform) subdirPlatformSpecific() string { return l.config.SubdirDarwinIOS }
 
-func (c *AppConfig) configDirSystem() (string, error) {
-	if isIOS {
+// Environment variables that are validated by `ValidateXDGEnv`.
+func (l darwinPlatform) xdgEnvVars() []string { return []string{"XDG_BIN_HOME"} }
+
+func (l darwinPlatform) configDirSystem() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedAppDirsSystemIOS
 	}
 	return "/Library/Application Support", nil
 }
 
-func (c *AppConfig) configDirLocal() (string, error) {
-	home, err := homedir.Dir()
+func (l darwinPlatform) configDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Library/Application Support"), nil
 }
 
-func (c *AppConfig) stateDirSystem() (string, error) {
-	if isIOS {
+func (l darwinPlatform) stateDirSystem() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedAppDirsSystemIOS
 	}
 	return "/Library/Application Support", nil
 }
 
-func (c *AppConfig) stateDirLocal() (string, error) {
-	home, err := homedir.Dir()
+func (l darwinPlatform) stateDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Library/Application Support"), nil
 }
 
-func (c *AppConfig) cacheDirSystem() (string, error) {
-	if isIOS {
+func (l darwinPlatform) cacheDirSystem() (string, error) {
+	if l.ios {
 		return "", ErrOSNotSupportedAppDirsSystemIOS
 	}
 	return "/Library/Caches", nil
 }
 
-func (c *AppConfig) cacheDirLocal() (string, error) {
-	home, err := homedir.Dir()
+func (l darwinPlatform) cacheDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "Library/Caches"), nil
 }
+
+func (l darwinPlatform) dataDirSystem() (string, error) {
+	if l.ios {
+		return "", ErrOSNotSupportedAppDirsSystemIOS
+	}
+	return "/Library/Application Support", nil
+}
+
+func (l darwinPlatform) dataDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(home, "Library/Application Support"), nil
+}
+
+func (l darwinPlatform) runtimeDirSystem() (string, error) {
+	if l.ios {
+		return "", ErrOSNotSupportedAppDirsSystemIOS
+	}
+	return "/var/run", nil
+}
+
+// $TMPDIR is per-user on macOS and inside the sandbox on iOS.
+func (l darwinPlatform) runtimeDirLocal() (string, error) {
+	dir := l.env.Getenv("TMPDIR")
+	if dir == "" {
+		dir = "/tmp"
+	}
+	return path.Clean(dir), nil
+}
+
+// System-wide search directories other than `configDirSystem`.
+func (l darwinPlatform) configSearchDirsSystem() ([]string, error) { return nil, nil }
+
+// System-wide search directories other than `dataDirSystem`.
+func (l darwinPlatform) dataSearchDirsSystem() ([]string, error) { return nil, nil }
+
+func (l darwinPlatform) pathEnvVar() string { return "PATH" }
+
+func (l darwinPlatform) pathListSeparator() byte { return ':' }
+
+// APFS and HFS+ are case-insensitive by default.
+func (l darwinPlatform) caseInsensitivePaths() bool { return true }
+
+func (l darwinPlatform) binDirSystem() (string, error) {
+	if l.ios {
+		return "", ErrOSNotSupportedBinDir
+	}
+	return "/usr/local/bin", nil
+}
+
+func (l darwinPlatform) binDirLocal() (string, error) {
+	if l.ios {
+		return "", ErrOSNotSupportedBinDir
+	}
+	dir, err := l.xdgEnv("XDG_BIN_HOME")
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		home, err := l.env.HomeDir()
+		if err != nil {
+			return "", err
+		}
+		return path.Join(home, ".local/bin"), nil
+	}
+	return dir, nil
+}
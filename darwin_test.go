@@ -14,6 +14,7 @@ func TestDarwinAppDirsSystem(t *testing.T) {
 		Subdir:      "foo/bar",
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(true, config)
 	require.NoError(t, err)
@@ -21,6 +22,7 @@ func TestDarwinAppDirsSystem(t *testing.T) {
 	require.Equal(t, "/Library/Application Support/foo/bar", d.ConfigDir)
 	require.Equal(t, "/Library/Application Support/foo/bar/state", d.StateDir)
 	require.Equal(t, "/Library/Caches/foo/bar", d.CacheDir)
+	require.Equal(t, "/Library/Application Support/foo/bar/data", d.DataDir)
 }
 
 func TestDarwinAppDirsLocal(t *testing.T) {
@@ -28,6 +30,7 @@ func TestDarwinAppDirsLocal(t *testing.T) {
 		Subdir:      "foo/bar",
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -37,6 +40,7 @@ func TestDarwinAppDirsLocal(t *testing.T) {
 	require.Equal(t, home+"/Library/Application Support/foo/bar", d.ConfigDir)
 	require.Equal(t, home+"/Library/Application Support/foo/bar/state", d.StateDir)
 	require.Equal(t, home+"/Library/Caches/foo/bar", d.CacheDir)
+	require.Equal(t, home+"/Library/Application Support/foo/bar/data", d.DataDir)
 }
 
 func TestDarwinAppDirsSubdirDarwinIOS(t *testing.T) {
@@ -45,6 +49,7 @@ func TestDarwinAppDirsSubdirDarwinIOS(t *testing.T) {
 		SubdirDarwinIOS: "zoo/zar",
 		SubdirState:     "state",
 		SubdirCache:     "cache",
+		SubdirData:      "data",
 	}
 	d, err := RetrieveAppDirs(true, config)
 	require.NoError(t, err)
@@ -52,6 +57,7 @@ func TestDarwinAppDirsSubdirDarwinIOS(t *testing.T) {
 	require.Equal(t, "/Library/Application Support/zoo/zar", d.ConfigDir)
 	require.Equal(t, "/Library/Application Support/zoo/zar/state", d.StateDir)
 	require.Equal(t, "/Library/Caches/zoo/zar", d.CacheDir)
+	require.Equal(t, "/Library/Application Support/zoo/zar/data", d.DataDir)
 
 	d, err = RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -61,6 +67,7 @@ func TestDarwinAppDirsSubdirDarwinIOS(t *testing.T) {
 	require.Equal(t, home+"/Library/Application Support/zoo/zar", d.ConfigDir)
 	require.Equal(t, home+"/Library/Application Support/zoo/zar/state", d.StateDir)
 	require.Equal(t, home+"/Library/Caches/zoo/zar", d.CacheDir)
+	require.Equal(t, home+"/Library/Application Support/zoo/zar/data", d.DataDir)
 }
 
 func TestDarwinUserDirs(t *testing.T) {
@@ -88,3 +95,29 @@ func TestDarwinUserDirs(t *testing.T) {
 		d.Fonts,
 	)
 }
+
+func TestDarwinSearchDirs(t *testing.T) {
+	config := &AppConfig{Subdir: "foo/bar"}
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+
+	dirs, err := RetrieveConfigSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/Library/Application Support/foo/bar", "/Library/Application Support/foo/bar"}, dirs)
+	dirs, err = RetrieveDataSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/Library/Application Support/foo/bar", "/Library/Application Support/foo/bar"}, dirs)
+}
+
+func TestDarwinBinDir(t *testing.T) {
+	dir, err := RetrieveBinDir(true)
+	require.NoError(t, err)
+	require.Equal(t, "/usr/local/bin", dir)
+
+	t.Setenv("XDG_BIN_HOME", "")
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+	dir, err = RetrieveBinDir(false)
+	require.NoError(t, err)
+	require.Equal(t, home+"/.local/bin", dir)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package finddirs
+
+import "golang.org/x/sys/windows"
+
+// freeSpace returns the number of bytes available to the current user on
+// the volume `dir` resides in. `ok` is false if it cannot be determined.
+func freeSpace(dir string) (free int64, ok bool) {
+	name, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, false
+	}
+	var available uint64
+	if windows.GetDiskFreeSpaceEx(name, &available, nil, nil) != nil {
+		return 0, false
+	}
+	return int64(available), true
+}
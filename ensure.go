@@ -0,0 +1,208 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+type EnsureConfig struct {
+	// Modes to create directories of given kinds with. Kinds that are not in
+	// the map are created with the default mode (see `EnsureDirs`).
+	//
+	// Example: map[DirKind]fs.FileMode{KindData: 0o750}
+	Modes map[DirKind]fs.FileMode
+}
+
+// EnsureDirs creates the directories in `d` that don't exist, and returns
+// the ones it created. `config` can be nil.
+//
+// Directories that are only meant to be used by the owner are created with
+// mode 0700: all user-level directories, and system-wide state and runtime
+// directories. Other system-wide directories are created with mode 0755.
+// Parent directories that don't exist are created with the same mode.
+// Modes of existing directories are not changed.
+//
+// If a path exists, but it is not a directory, `ErrNotDirectory` is
+// returned. If it is a symbolic link to a file that doesn't exist,
+// `ErrDanglingSymlink` is returned.
+//
+// On Unix, the runtime directory and its parents are checked before and
+// after it is created: they must be owned by the current user or root, and
+// directories that other users can write to must be sticky (e.g. /tmp).
+// Otherwise `ErrRuntimeDirUnsafe` is returned, so that the fallback runtime
+// directory that another user has created first is not used.
+func (d *AppDirs) EnsureDirs(config *EnsureConfig) (created []string, err error) {
+	if config == nil {
+		config = new(EnsureConfig)
+	}
+	for _, kind := range dirKinds {
+		dir := d.Dir(kind)
+		// Directories of different kinds might be the same directory.
+		if dir == "" || contains(created, dir) {
+			continue
+		}
+		mode, ok := config.Modes[kind]
+		if !ok {
+			mode = defaultDirMode(kind, d.SystemWide)
+		}
+		ensure := ensureDir
+		if kind == KindRuntime {
+			ensure = ensureRuntimeDirPath
+		}
+		ok, err := ensure(dir, mode)
+		if err != nil {
+			return created, fmt.Errorf("finddirs: %w", err)
+		}
+		if ok {
+			created = append(created, dir)
+		}
+	}
+	return
+}
+
+// EnsureRuntimeDir makes sure the local runtime directory of the current
+// user is safe to use, as XDG Base Directory Specification requires: it
+// must be a directory that is owned by the user, and its mode must be 0700
+// (it is changed if it isn't). Otherwise `ErrRuntimeDirUnsafe` is returned.
+// `config` can be nil.
+//
+// If $XDG_RUNTIME_DIR is unset, the fallback directory inside the temporary
+// directory is created first. The application directory is not created;
+// use `EnsureDirs` for it.
+//
+// `RetrieveAppDirs` doesn't check the runtime directory, so call this before
+// using `RuntimeDir`. If the runtime directory is overridden through an
+// environment variable (see `EnvPrefix`), or on systems other than Unix,
+// nothing is done.
+func EnsureRuntimeDir(config *AppConfig) error {
+	return defaultResolver.EnsureRuntimeDir(config)
+}
+
+// EnsureRuntimeDir is the same as `EnsureRuntimeDir`, but it uses the
+// environment of the resolver. With a `UserEnv`, the directory must be owned
+// by its user, and the fallback directory is created for them.
+func (r *Resolver) EnsureRuntimeDir(config *AppConfig) error {
+	l := r.newLookup(config, nil)
+	p, ok := l.platform.(unixPlatform)
+	if !ok {
+		return nil
+	}
+	_, ok, err := l.envOverride(KindRuntime)
+	if err == nil && !ok {
+		err = p.ensureRuntimeDir()
+	}
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	return nil
+}
+
+func defaultDirMode(kind DirKind, systemWide bool) fs.FileMode {
+	if !systemWide || kind == KindState || kind == KindRuntime {
+		return 0o700
+	}
+	return 0o755
+}
+
+// ensureDir creates `dir` with `mode` if it doesn't exist, and reports
+// whether it was created.
+func ensureDir(dir string, mode fs.FileMode) (created bool, err error) {
+	dir = filepath.FromSlash(dir)
+	fi, err := os.Stat(dir)
+	if err == nil {
+		if !fi.IsDir() {
+			return false, fmt.Errorf("%w: %s", ErrNotDirectory, dir)
+		}
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if fi, err := os.Lstat(dir); err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+		return false, fmt.Errorf("%w: %s", ErrDanglingSymlink, dir)
+	}
+
+	err = os.MkdirAll(dir, mode)
+	if err != nil {
+		return false, err
+	}
+	// Mode passed to MkdirAll is masked by umask.
+	return true, os.Chmod(dir, mode)
+}
+
+// ensureRuntimeDirPath is the same as `ensureDir`, but `dir` and its parents
+// are checked by `checkRuntimeDirPath` before and after it is created.
+func ensureRuntimeDirPath(dir string, mode fs.FileMode) (created bool, err error) {
+	err = checkRuntimeDirPath(dir)
+	if err != nil {
+		return false, err
+	}
+	created, err = ensureDir(dir, mode)
+	if err != nil {
+		return created, err
+	}
+	return created, checkRuntimeDirPath(dir)
+}
+
+// checkRuntimeDirPath checks whether `dir` and its parents can only be
+// changed by the current user and root, so that no other user can replace
+// the runtime directory or files inside it. Directories that don't exist
+// yet are skipped, and directories that others can write to must be sticky
+// (e.g. /tmp). Otherwise `ErrRuntimeDirUnsafe` is returned.
+//
+// On systems without Unix permissions, nothing is checked.
+func checkRuntimeDirPath(dir string) error {
+	if !ownersSupported {
+		return nil
+	}
+	uid := os.Getuid()
+	dir = filepath.Clean(filepath.FromSlash(dir))
+	for {
+		_, err := os.Lstat(dir)
+		if err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+	// Symbolic links are resolved, so that the directories they point to
+	// are checked instead.
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	for {
+		fi, err := os.Lstat(dir)
+		if err != nil {
+			return err
+		}
+		owner, ok := fileOwner(fi)
+		if !ok || owner != uid && owner != 0 {
+			return fmt.Errorf("%w: %s is not owned by the user (UID %d)", ErrRuntimeDirUnsafe, filepath.ToSlash(dir), uid)
+		}
+		if fi.Mode()&0o022 != 0 && fi.Mode()&fs.ModeSticky == 0 {
+			return fmt.Errorf("%w: %s can be written by other users", ErrRuntimeDirUnsafe, filepath.ToSlash(dir))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package finddirs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureDirs(t *testing.T) {
+	root := filepath.ToSlash(t.TempDir())
+	d := &AppDirs{
+		ConfigDir:  root + "/etc/foo",
+		StateDir:   root + "/var/lib/foo",
+		CacheDir:   root + "/var/cache/foo",
+		DataDir:    root + "/etc/foo",
+		RuntimeDir: root + "/run/foo",
+		SystemWide: true,
+	}
+	require.NoError(t, os.MkdirAll(root+"/var/cache/foo", 0o755))
+
+	created, err := d.EnsureDirs(&EnsureConfig{Modes: map[DirKind]os.FileMode{KindRuntime: 0o750}})
+	require.NoError(t, err)
+	require.Equal(t, []string{d.ConfigDir, d.StateDir, d.RuntimeDir}, created)
+
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" {
+		for dir, mode := range map[string]os.FileMode{
+			d.ConfigDir:  0o755,
+			d.StateDir:   0o700,
+			d.RuntimeDir: 0o750,
+		} {
+			fi, err := os.Stat(dir)
+			require.NoError(t, err)
+			require.Equal(t, mode, fi.Mode().Perm(), dir)
+		}
+	}
+
+	created, err = d.EnsureDirs(nil)
+	require.NoError(t, err)
+	require.Empty(t, created)
+
+	// A file
+	require.NoError(t, os.WriteFile(root+"/file", nil, 0o644))
+	d = &AppDirs{ConfigDir: root + "/file"}
+	_, err = d.EnsureDirs(nil)
+	require.ErrorIs(t, err, ErrNotDirectory)
+
+	// A dangling symbolic link
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" {
+		require.NoError(t, os.Symlink(root+"/nonexistent", root+"/link"))
+		d = &AppDirs{ConfigDir: root + "/link"}
+		_, err = d.EnsureDirs(nil)
+		require.ErrorIs(t, err, ErrDanglingSymlink)
+	}
+}
+
+func TestEnsureDirsRuntimeUnsafe(t *testing.T) {
+	if !ownersSupported {
+		t.Skip("file owners are not supported on", runtime.GOOS)
+	}
+	// Anyone can create files inside it.
+	root := t.TempDir()
+	require.NoError(t, os.Chmod(root, 0o777))
+	d := &AppDirs{
+		ConfigDir:  filepath.ToSlash(t.TempDir()) + "/foo",
+		RuntimeDir: filepath.ToSlash(root) + "/runtime-1000/foo",
+	}
+	created, err := d.EnsureDirs(nil)
+	require.ErrorIs(t, err, ErrRuntimeDirUnsafe)
+	require.Equal(t, []string{d.ConfigDir}, created)
+	_, err = os.Stat(filepath.Join(root, "runtime-1000"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// Sticky directories are fine.
+	require.NoError(t, os.Chmod(root, 0o777|os.ModeSticky))
+	created, err = d.EnsureDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{d.RuntimeDir}, created)
+
+	if os.Getuid() == 0 {
+		// Created by another user first
+		require.NoError(t, os.RemoveAll(filepath.Join(root, "runtime-1000")))
+		require.NoError(t, os.Mkdir(filepath.Join(root, "runtime-1000"), 0o700))
+		require.NoError(t, os.Chown(filepath.Join(root, "runtime-1000"), 65534, 65534))
+		_, err = d.EnsureDirs(nil)
+		require.ErrorIs(t, err, ErrRuntimeDirUnsafe)
+	}
+}
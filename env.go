@@ -0,0 +1,61 @@
+package finddirs
+
+import "strings"
+
+// MissingEnvError is returned when `RequireEnv` is set, and directories
+// of some kinds are not set through environment variables.
+type MissingEnvError struct {
+	// Kinds of the directories that are not set.
+	Kinds []DirKind
+	// Names of the environment variables that are not set. Empty if
+	// there is no environment variable defined for the particular kind.
+	Vars []string
+}
+
+func (e *MissingEnvError) Error() string {
+	kinds := make([]string, len(e.Kinds))
+	for i, kind := range e.Kinds {
+		kinds[i] = kind.String()
+		if e.Vars[i] != "" {
+			kinds[i] += " ($" + e.Vars[i] + ")"
+		}
+	}
+	return "directories are not set through environment variables: " + strings.Join(kinds, ", ")
+}
+
+// envVar returns the name of the environment variable that overrides the
+// directory of `kind`. Empty if there is none.
+func (c *AppConfig) envVar(kind DirKind) string {
+	if name, ok := c.EnvVars[kind]; ok {
+		return name
+	}
+	if c.EnvPrefix == "" {
+		return ""
+	}
+	return c.EnvPrefix + "_" + strings.ToUpper(kind.String()) + "_DIR"
+}
+
+// envOverride returns the directory of `kind` set through an environment
+// variable. If the value is not an absolute path, `*InvalidEnvError` is returned.
+func (l *lookup) envOverride(kind DirKind) (dir string, ok bool, err error) {
+	name := l.config.envVar(kind)
+	if name == "" {
+		return "", false, nil
+	}
+	dir, invalid, err := envDir(name, l.env.Getenv(name), l.config.ExpandTilde, l.env.HomeDir)
+	if err != nil {
+		return "", false, err
+	}
+	if invalid != nil {
+		return "", false, invalid
+	}
+	return dir, dir != "", nil
+}
+
+func (c *AppConfig) missingEnvError(kinds []DirKind) error {
+	e := &MissingEnvError{Kinds: kinds}
+	for _, kind := range kinds {
+		e.Vars = append(e.Vars, c.envVar(kind))
+	}
+	return e
+}
@@ -3,6 +3,21 @@ package finddirs
 import "fmt"
 
 var (
+	ErrOSNotSupported                 = fmt.Errorf("operating system is not supported")
 	ErrOSNotSupportedUserDirs         = fmt.Errorf("RetrieveUserDirs doesn't support this operating system")
 	ErrOSNotSupportedAppDirsSystemIOS = fmt.Errorf("cannot get system-wide app directories: iOS apps are inside a sandbox, therefore iOS apps cannot have system-wide app directories")
+	ErrAppDirsSystemFlatpak           = fmt.Errorf("cannot get system-wide app directories: Flatpak apps are inside a sandbox, where system-wide directories belong to the Flatpak runtime")
+	ErrOSNotSupportedSetUserDirs      = fmt.Errorf("SetUserDir doesn't support this operating system")
+	ErrOSNotSupportedBinDir           = fmt.Errorf("RetrieveBinDir doesn't support this operating system")
+	ErrEnvReadOnly                    = fmt.Errorf("files cannot be written in this environment")
+	ErrUserPathUnsafe                 = fmt.Errorf("path is not safe to write to on behalf of the user")
+	ErrOSNotSupportedUserEnv          = fmt.Errorf("environments of other users are not supported on this operating system")
+	ErrOSNotSupportedAudit            = fmt.Errorf("Audit doesn't support this operating system")
+	ErrOSNotSupportedLock             = fmt.Errorf("file locks are not supported on this operating system")
+	ErrLocked                         = fmt.Errorf("locked by another process")
+	ErrRuntimeDirUnsafe               = fmt.Errorf("runtime directory is not safe to use")
+	ErrFileNotFound                   = fmt.Errorf("file not found in any of the search directories")
+	ErrNotDirectory                   = fmt.Errorf("path exists, but it is not a directory")
+	ErrDanglingSymlink                = fmt.Errorf("path is a symbolic link to a file that doesn't exist")
+	ErrInvalidFileName                = fmt.Errorf("file name must be a relative path inside the directory")
 )
@@ -0,0 +1,12 @@
+//go:build !plan9 && !windows
+
+package finddirs
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDeviceError reports whether `err` is returned by os.Rename because
+// the destination is on another filesystem.
+func isCrossDeviceError(err error) bool { return errors.Is(err, syscall.EXDEV) }
@@ -0,0 +1,13 @@
+//go:build plan9
+
+package finddirs
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// isCrossDeviceError reports whether `err` is returned by os.Rename because
+// the destination cannot be renamed to. On Plan 9, files can only be renamed
+// inside the same directory; otherwise fs.ErrInvalid is returned.
+func isCrossDeviceError(err error) bool { return errors.Is(err, fs.ErrInvalid) }
@@ -0,0 +1,13 @@
+//go:build windows
+
+package finddirs
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isCrossDeviceError reports whether `err` is returned by os.Rename because
+// the destination is on another volume.
+func isCrossDeviceError(err error) bool { return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE) }
@@ -0,0 +1,114 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Written by Flatpak into every sandbox.
+const flatpakInfoPath = "/.flatpak-info"
+
+// FlatpakInfo describes the Flatpak sandbox the application runs inside.
+type FlatpakInfo struct {
+	// ID of the application (e.g. "org.example.App").
+	AppID string
+	// ID of the running instance of the sandbox. Empty if /.flatpak-info
+	// cannot be read.
+	InstanceID string
+	// Runtime the application uses (e.g. "runtime/org.freedesktop.Platform/x86_64/23.08").
+	// Empty if /.flatpak-info cannot be read.
+	Runtime string
+
+	// Directory of the application inside the home directory, ~/.var/app/<AppID>.
+	// Inside the sandbox, $XDG_CONFIG_HOME, $XDG_DATA_HOME, $XDG_STATE_HOME and
+	// $XDG_CACHE_HOME point to directories inside it.
+	AppDir string
+	// Mount point of the document portal, $XDG_RUNTIME_DIR/doc. Files the user
+	// grants access to (e.g. through a file chooser) are made available
+	// inside it. Empty if $XDG_RUNTIME_DIR is unset.
+	DocumentPortalDir string
+}
+
+// RetrieveFlatpakInfo returns information about the Flatpak sandbox the
+// current process runs inside. Flatpak is detected through /.flatpak-info
+// and $FLATPAK_ID. If not running inside Flatpak, nil is returned.
+//
+// Inside Flatpak, system-wide directories belong to the runtime, not to the
+// host, hence `RetrieveAppDirs` returns `ErrAppDirsSystemFlatpak` for them.
+func RetrieveFlatpakInfo() (*FlatpakInfo, error) {
+	return defaultResolver.FlatpakInfo()
+}
+
+// FlatpakInfo is the same as `RetrieveFlatpakInfo`, but it uses the
+// environment of the resolver. On systems other than Unix, nil is returned.
+func (r *Resolver) FlatpakInfo() (*FlatpakInfo, error) {
+	l := r.newLookup(nil, nil)
+	p, ok := l.platform.(unixPlatform)
+	if !ok || !p.flatpak() {
+		return nil, nil
+	}
+
+	info := &FlatpakInfo{AppID: l.env.Getenv("FLATPAK_ID")}
+	data, err := l.env.ReadFile(flatpakInfoPath)
+	if err == nil {
+		keys := parseFlatpakInfo(data)
+		if info.AppID == "" {
+			info.AppID = keys["Application.name"]
+		}
+		info.InstanceID = keys["Instance.instance-id"]
+		info.Runtime = keys["Application.runtime"]
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+
+	if info.AppID != "" {
+		home, err := l.env.HomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("finddirs: %w", err)
+		}
+		info.AppDir = path.Join(home, ".var/app", info.AppID)
+	}
+	runtimeDir, err := l.xdgEnv("XDG_RUNTIME_DIR")
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	if runtimeDir != "" {
+		info.DocumentPortalDir = path.Join(runtimeDir, "doc")
+	}
+	return info, nil
+}
+
+// flatpak reports whether running inside a Flatpak sandbox.
+func (l unixPlatform) flatpak() bool {
+	if !l.flatpakChecked {
+		_, err := l.env.Stat(flatpakInfoPath)
+		l.isFlatpak = err == nil || l.env.Getenv("FLATPAK_ID") != ""
+		l.flatpakChecked = true
+	}
+	return l.isFlatpak
+}
+
+// parseFlatpakInfo parses /.flatpak-info, which is a key file (just like
+// .desktop files), and returns its entries as `<group>.<key>`.
+func parseFlatpakInfo(data []byte) map[string]string {
+	keys := make(map[string]string)
+	group := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = line[1 : len(line)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok {
+			keys[group+"."+strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return keys
+}
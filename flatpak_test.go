@@ -0,0 +1,75 @@
+package finddirs
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatpak(t *testing.T) {
+	appDir := "/home/foo/.var/app/org.example.Foo"
+	env := &fakeEnv{
+		home: "/home/foo",
+		vars: map[string]string{
+			"XDG_CONFIG_HOME": appDir + "/config",
+			"XDG_DATA_HOME":   appDir + "/data",
+			"XDG_CACHE_HOME":  appDir + "/cache",
+			"XDG_STATE_HOME":  appDir + "/.local/state",
+			"XDG_CONFIG_DIRS": "/app/etc/xdg:/etc/xdg",
+			"XDG_RUNTIME_DIR": "/run/user/1000",
+		},
+		files: fstest.MapFS{
+			".flatpak-info": {Data: []byte("[Application]\nname=org.example.Foo\nruntime=runtime/org.freedesktop.Platform/x86_64/23.08\n\n[Instance]\ninstance-id=123\n")},
+			// user-dirs.dirs of the host
+			"home/foo/.config/user-dirs.dirs": {Data: []byte("XDG_DOWNLOAD_DIR=\"$HOME/dl\"\n")},
+		},
+	}
+	r, err := NewResolverForOS("linux", env)
+	require.NoError(t, err)
+
+	info, err := r.FlatpakInfo()
+	require.NoError(t, err)
+	require.Equal(t, &FlatpakInfo{
+		AppID:             "org.example.Foo",
+		InstanceID:        "123",
+		Runtime:           "runtime/org.freedesktop.Platform/x86_64/23.08",
+		AppDir:            appDir,
+		DocumentPortalDir: "/run/user/1000/doc",
+	}, info)
+
+	config := &AppConfig{Subdir: "foo"}
+	d, err := r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, appDir+"/config/foo", d.ConfigDir)
+	require.Equal(t, appDir+"/data/foo", d.DataDir)
+
+	_, err = r.AppDirs(true, config)
+	require.ErrorIs(t, err, ErrAppDirsSystemFlatpak)
+	_, err = r.BinDir(true, nil)
+	require.ErrorIs(t, err, ErrAppDirsSystemFlatpak)
+
+	dirs, err := r.ConfigSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{appDir + "/config/foo", "/app/etc/xdg/foo", "/etc/xdg/foo"}, dirs)
+
+	u, err := r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, "/home/foo/dl", u.Downloads)
+
+	// Detected through $FLATPAK_ID
+	delete(env.files, ".flatpak-info")
+	env.vars["FLATPAK_ID"] = "org.example.Bar"
+	info, err = r.FlatpakInfo()
+	require.NoError(t, err)
+	require.Equal(t, "org.example.Bar", info.AppID)
+	require.Equal(t, "/home/foo/.var/app/org.example.Bar", info.AppDir)
+
+	// Not inside Flatpak
+	delete(env.vars, "FLATPAK_ID")
+	info, err = r.FlatpakInfo()
+	require.NoError(t, err)
+	require.Nil(t, info)
+	_, err = r.AppDirs(true, config)
+	require.NoError(t, err)
+}
@@ -0,0 +1,157 @@
+//go:build linux
+
+package finddirs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_ATTRIB | unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_DELETE_SELF | unix.IN_MODIFY | unix.IN_MOVE_SELF | unix.IN_MOVED_FROM |
+	unix.IN_MOVED_TO | unix.IN_ONLYDIR
+
+type inotify struct {
+	fd   int
+	f    *os.File
+	ch   chan string
+	done chan struct{}
+
+	mu  sync.Mutex
+	wds map[string]int
+	// Different paths of the same directory (e.g. a symbolic link and the
+	// directory it points to) share a watch.
+	dirs map[int][]string
+}
+
+func newNotifier() (notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	n := &inotify{
+		fd: fd,
+		// Since the file descriptor is non-blocking, reads wait in the
+		// runtime poller, and they are interrupted by `Close`.
+		f:    os.NewFile(uintptr(fd), "inotify"),
+		ch:   make(chan string, 64),
+		done: make(chan struct{}),
+		wds:  make(map[string]int),
+		dirs: make(map[int][]string),
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) watch(dirs []string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var errs []error
+	watched := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		watched[dir] = true
+		if _, ok := n.wds[dir]; ok {
+			continue
+		}
+		wd, err := unix.InotifyAddWatch(n.fd, dir, inotifyMask)
+		if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENOTDIR) {
+			// Removed after it was scanned. Its removal is reported.
+			continue
+		} else if err != nil {
+			errs = append(errs, &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err})
+			continue
+		}
+		n.wds[dir] = wd
+		n.dirs[wd] = append(n.dirs[wd], dir)
+	}
+
+	for dir, wd := range n.wds {
+		if watched[dir] {
+			continue
+		}
+		delete(n.wds, dir)
+		n.dirs[wd] = removeString(n.dirs[wd], dir)
+		if len(n.dirs[wd]) == 0 {
+			delete(n.dirs, wd)
+			unix.InotifyRmWatch(n.fd, uint32(wd))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *inotify) read() {
+	defer close(n.ch)
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		count, err := n.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= count; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+			if offset > count {
+				break
+			}
+			name := strings.TrimRight(string(buf[nameStart:offset]), "\x00")
+
+			n.mu.Lock()
+			dirs := n.dirs[int(event.Wd)]
+			if event.Mask&unix.IN_IGNORED != 0 {
+				// Directory is removed, or the watch is removed.
+				delete(n.dirs, int(event.Wd))
+				for _, dir := range dirs {
+					if n.wds[dir] == int(event.Wd) {
+						delete(n.wds, dir)
+					}
+				}
+			}
+			n.mu.Unlock()
+
+			// If the queue has overflowed, there is no directory, and
+			// empty path is sent.
+			paths := []string{""}
+			if len(dirs) > 0 {
+				paths = paths[:0]
+				for _, dir := range dirs {
+					if name != "" {
+						dir = filepath.Join(dir, name)
+					}
+					paths = append(paths, dir)
+				}
+			}
+			for _, p := range paths {
+				select {
+				case n.ch <- p:
+				case <-n.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// removeString returns `s` without `v`.
+func removeString(s []string, v string) []string {
+	for i, e := range s {
+		if e == v {
+			return append(s[:i:i], s[i+1:]...)
+		}
+	}
+	return s
+}
+
+func (n *inotify) changes() <-chan string { return n.ch }
+
+func (n *inotify) close() error {
+	close(n.done)
+	return n.f.Close()
+}
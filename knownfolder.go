@@ -0,0 +1,39 @@
+//go:build windows
+
+package finddirs
+
+import (
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+var knownFolderIDs = map[KnownFolder]*windows.KNOWNFOLDERID{
+	KnownFolderDesktop:          windows.FOLDERID_Desktop,
+	KnownFolderDownloads:        windows.FOLDERID_Downloads,
+	KnownFolderDocuments:        windows.FOLDERID_Documents,
+	KnownFolderPictures:         windows.FOLDERID_Pictures,
+	KnownFolderVideos:           windows.FOLDERID_Videos,
+	KnownFolderMusic:            windows.FOLDERID_Music,
+	KnownFolderFonts:            windows.FOLDERID_Fonts,
+	KnownFolderTemplates:        windows.FOLDERID_Templates,
+	KnownFolderPublic:           windows.FOLDERID_Public,
+	KnownFolderRoamingAppData:   windows.FOLDERID_RoamingAppData,
+	KnownFolderLocalAppData:     windows.FOLDERID_LocalAppData,
+	KnownFolderProgramData:      windows.FOLDERID_ProgramData,
+	KnownFolderProgramFiles:     windows.FOLDERID_ProgramFiles,
+	KnownFolderUserProgramFiles: windows.FOLDERID_UserProgramFiles,
+}
+
+func (OSEnv) KnownFolderPath(folder KnownFolder) (path string, err error) {
+	id := knownFolderIDs[folder]
+	flags := []uint32{windows.KF_FLAG_DEFAULT, windows.KF_FLAG_DEFAULT_PATH}
+	for _, flag := range flags {
+		path, err = windows.KnownFolderPath(id, flag|windows.KF_FLAG_DONT_VERIFY)
+		if err == nil {
+			path = filepath.Clean(path)
+			return
+		}
+	}
+	return
+}
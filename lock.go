@@ -0,0 +1,228 @@
+package finddirs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// Name of the lock file if `LockConfig.Name` is empty.
+const defaultLockFileName = "instance.lock"
+
+type LockConfig struct {
+	// Name of the lock file inside the runtime directory (or the state
+	// directory if the runtime directory is empty). Applications that need
+	// more than one lock can use different names.
+	//
+	// If empty, "instance.lock" is used.
+	Name string
+
+	// If true, PID of the current process and the host name are not
+	// written to the lock file.
+	NoPID bool
+
+	// Written to the lock file. Processes that cannot acquire the lock can read
+	// it from `LockInfo` (e.g. the address the running instance listens on).
+	Metadata string
+}
+
+// LockInfo is written to the lock file by the process that holds the lock.
+type LockInfo struct {
+	// PID of the process and the name of the host it runs on.
+	// Empty if `NoPID` is set.
+	PID      int       `json:"pid,omitempty"`
+	Hostname string    `json:"hostname,omitempty"`
+	Acquired time.Time `json:"acquired"`
+	Metadata string    `json:"metadata,omitempty"`
+}
+
+// LockedError is returned by `TryLock` if the lock is held by another process.
+// It satisfies errors.Is(err, ErrLocked).
+type LockedError struct {
+	// Path of the lock file.
+	Path string
+	// Information about the process that holds the lock. Nil if it
+	// couldn't be read (e.g. it is being written).
+	Info *LockInfo
+}
+
+func (e *LockedError) Error() string {
+	if e.Info != nil && e.Info.PID != 0 {
+		return fmt.Sprintf("%s is %s (PID %d)", e.Path, ErrLocked, e.Info.PID)
+	}
+	return fmt.Sprintf("%s is %s", e.Path, ErrLocked)
+}
+
+func (e *LockedError) Unwrap() error { return ErrLocked }
+
+// InstanceLock is an advisory lock on a file inside the runtime directory.
+// Use it to prevent an application from running more than once per user
+// (or once system-wide, if `AppDirs` are system-wide directories).
+//
+// The lock is released by `Unlock`, or by the operating system when the
+// process exits.
+type InstanceLock struct {
+	f    *os.File
+	path string
+
+	// If the previous holder of the lock terminated without calling `Unlock`
+	// (e.g. it crashed), information it had written to the lock file.
+	// Otherwise nil.
+	Stale *LockInfo
+}
+
+// Lock acquires the lock, and waits until it is released if it is held by
+// another process. `config` can be nil.
+//
+// If the lock file is inside the runtime directory, the runtime directory
+// and its parents must not be writable by other users (except root), as
+// `EnsureDirs` requires. Otherwise `ErrRuntimeDirUnsafe` is returned.
+//
+// File locks are supported on Linux, macOS, iOS, FreeBSD, NetBSD, OpenBSD,
+// DragonFly BSD, and Windows. On other systems, `ErrOSNotSupportedLock` is returned.
+func (d *AppDirs) Lock(config *LockConfig) (*InstanceLock, error) {
+	return d.lock(config, true)
+}
+
+// TryLock is the same as `Lock`, but if the lock is held by another process,
+// it returns `*LockedError` immediately instead of waiting.
+func (d *AppDirs) TryLock(config *LockConfig) (*InstanceLock, error) {
+	return d.lock(config, false)
+}
+
+// ReadLockInfo returns the information the process that holds (or held)
+// the lock has written to the lock file. If there is none, nil is returned.
+func (d *AppDirs) ReadLockInfo(config *LockConfig) (*LockInfo, error) {
+	if config == nil {
+		config = new(LockConfig)
+	}
+	lockPath, err := d.lockPath(config)
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	return parseLockInfo(data), nil
+}
+
+func (d *AppDirs) lock(config *LockConfig, wait bool) (*InstanceLock, error) {
+	if config == nil {
+		config = new(LockConfig)
+	}
+	lockPath, err := d.lockPath(config)
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	kind := KindRuntime
+	if d.RuntimeDir == "" {
+		kind = KindState
+	}
+	dir := filepath.Dir(lockPath)
+	if kind == KindRuntime {
+		// Otherwise another user could create the runtime directory (e.g.
+		// the fallback inside /tmp) first, and take over the lock.
+		_, err = ensureRuntimeDirPath(dir, defaultDirMode(kind, d.SystemWide))
+	} else {
+		_, err = ensureDir(dir, defaultDirMode(kind, d.SystemWide))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	err = lockFile(f, true, wait)
+	if err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			data, _ := os.ReadFile(lockPath)
+			err = &LockedError{Path: filepath.ToSlash(lockPath), Info: parseLockInfo(data)}
+		}
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+
+	l := &InstanceLock{f: f, path: lockPath}
+	// The file is emptied by `Unlock`. If it isn't empty, the previous holder
+	// didn't unlock it.
+	data, err := os.ReadFile(lockPath)
+	if err == nil {
+		l.Stale = parseLockInfo(data)
+	}
+
+	info := LockInfo{Acquired: time.Now(), Metadata: config.Metadata}
+	if !config.NoPID {
+		info.PID = os.Getpid()
+		info.Hostname, _ = os.Hostname()
+	}
+	data, err = json.Marshal(info)
+	if err == nil {
+		err = f.Truncate(0)
+	}
+	if err == nil {
+		_, err = f.WriteAt(append(data, '\n'), 0)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		l.Unlock()
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	return l, nil
+}
+
+func (d *AppDirs) lockPath(config *LockConfig) (string, error) {
+	name := config.Name
+	if name == "" {
+		name = defaultLockFileName
+	}
+	err := checkFileName(name)
+	if err != nil {
+		return "", err
+	}
+	dir := d.RuntimeDir
+	if dir == "" {
+		dir = d.StateDir
+	}
+	if dir == "" {
+		return "", fmt.Errorf("neither runtime nor state directory is set")
+	}
+	return filepath.FromSlash(path.Join(dir, filepath.ToSlash(name))), nil
+}
+
+// Unlock empties the lock file, and releases the lock. The lock file is not
+// removed, since removing it would race with other processes that are
+// waiting for the lock.
+func (l *InstanceLock) Unlock() error {
+	err := l.f.Truncate(0)
+	unlockErr := unlockFile(l.f)
+	closeErr := l.f.Close()
+	for _, e := range []error{err, unlockErr, closeErr} {
+		if e != nil {
+			return fmt.Errorf("finddirs: %w", e)
+		}
+	}
+	return nil
+}
+
+// Path returns the path of the lock file.
+func (l *InstanceLock) Path() string { return filepath.ToSlash(l.path) }
+
+func parseLockInfo(data []byte) *LockInfo {
+	info := new(LockInfo)
+	if json.Unmarshal(data, info) != nil {
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,96 @@
+package finddirs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	d := &AppDirs{RuntimeDir: filepath.ToSlash(t.TempDir()) + "/foo"}
+	config := &LockConfig{Metadata: "127.0.0.1:8080"}
+
+	l, err := d.TryLock(config)
+	if errors.Is(err, ErrOSNotSupportedLock) {
+		t.Skip(runtime.GOOS, err)
+	}
+	require.NoError(t, err)
+	require.Nil(t, l.Stale)
+	require.Equal(t, d.RuntimeDir+"/instance.lock", l.Path())
+
+	_, err = d.TryLock(config)
+	require.ErrorIs(t, err, ErrLocked)
+	var lockedErr *LockedError
+	require.ErrorAs(t, err, &lockedErr)
+	require.NotNil(t, lockedErr.Info)
+	require.Equal(t, os.Getpid(), lockedErr.Info.PID)
+	require.Equal(t, "127.0.0.1:8080", lockedErr.Info.Metadata)
+
+	// Another lock
+	l2, err := d.TryLock(&LockConfig{Name: "other.lock", NoPID: true})
+	require.NoError(t, err)
+	info, err := d.ReadLockInfo(&LockConfig{Name: "other.lock"})
+	require.NoError(t, err)
+	require.Equal(t, 0, info.PID)
+	require.NoError(t, l2.Unlock())
+
+	require.NoError(t, l.Unlock())
+	info, err = d.ReadLockInfo(config)
+	require.NoError(t, err)
+	require.Nil(t, info)
+
+	l, err = d.Lock(config)
+	require.NoError(t, err)
+	require.Nil(t, l.Stale)
+	// Simulate a crash: the lock is released, but the file is not emptied.
+	require.NoError(t, unlockFile(l.f))
+	require.NoError(t, l.f.Close())
+
+	l, err = d.TryLock(nil)
+	require.NoError(t, err)
+	require.NotNil(t, l.Stale)
+	require.Equal(t, os.Getpid(), l.Stale.PID)
+	require.NoError(t, l.Unlock())
+}
+
+func TestLockStateDir(t *testing.T) {
+	d := &AppDirs{StateDir: filepath.ToSlash(t.TempDir())}
+	l, err := d.TryLock(nil)
+	if errors.Is(err, ErrOSNotSupportedLock) {
+		t.Skip(runtime.GOOS, err)
+	}
+	require.NoError(t, err)
+	require.Equal(t, d.StateDir+"/instance.lock", l.Path())
+	require.NoError(t, l.Unlock())
+
+	_, err = d.TryLock(&LockConfig{Name: "../escape"})
+	require.ErrorIs(t, err, ErrInvalidFileName)
+}
+
+func TestLockRuntimeDirUnsafe(t *testing.T) {
+	if !ownersSupported {
+		t.Skip("file owners are not supported on", runtime.GOOS)
+	}
+	// Anyone can create files inside it.
+	runtimeDir := t.TempDir()
+	require.NoError(t, os.Chmod(runtimeDir, 0o777))
+	d := &AppDirs{RuntimeDir: filepath.ToSlash(runtimeDir) + "/foo"}
+	_, err := d.TryLock(nil)
+	if errors.Is(err, ErrOSNotSupportedLock) {
+		t.Skip(runtime.GOOS, err)
+	}
+	require.ErrorIs(t, err, ErrRuntimeDirUnsafe)
+	_, err = os.Stat(filepath.Join(runtimeDir, "foo"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	if os.Getuid() == 0 {
+		require.NoError(t, os.Chmod(runtimeDir, 0o700))
+		require.NoError(t, os.Chown(runtimeDir, 65534, 65534))
+		_, err = d.TryLock(nil)
+		require.ErrorIs(t, err, ErrRuntimeDirUnsafe)
+	}
+}
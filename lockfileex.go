@@ -0,0 +1,38 @@
+//go:build windows
+
+package finddirs
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes a lock on `f`. If `wait` is false, and the file is
+// locked by another process, `ErrLocked` is returned.
+func lockFile(f *os.File, exclusive, wait bool) error {
+	var flags uint32
+	if exclusive {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	if !wait {
+		flags |= windows.LOCKFILE_FAIL_IMMEDIATELY
+	}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, 1, 0, lockRange())
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrLocked
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, lockRange())
+}
+
+// lockRange returns the offset of the byte that is locked. Locked regions
+// cannot be read by other processes on Windows, so a byte far beyond the end
+// of the file is locked instead of the contents.
+func lockRange() *windows.Overlapped {
+	return &windows.Overlapped{OffsetHigh: 0x7fffffff}
+}
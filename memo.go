@@ -0,0 +1,216 @@
+package finddirs
+
+import (
+	"fmt"
+	"io/fs"
+	"sync"
+)
+
+type MemoConfig struct {
+	// If true, a memoized value is resolved again when the environment
+	// variables or the files (e.g. user-dirs.dirs) it was resolved from
+	// change. They are checked on every call, which costs an environment
+	// variable lookup and a stat per dependency, but no process is run.
+	AutoInvalidate bool
+}
+
+// Memoize returns a resolver that uses the same environment as `r`, but
+// resolves each value once, and returns the memoized value afterwards.
+// Values are memoized per method and arguments (e.g. per `AppConfig`).
+// Errors are not memoized. `config` can be nil.
+//
+// Values are memoized by `AppDirs`, `UserDirs`, `ConfigSearchDirs`,
+// `DataSearchDirs` and `BinDir`. Since `Find*` methods use the search
+// directories, only the existence of files is checked by them.
+//
+// The returned resolver is safe for concurrent use. Call `Invalidate` to
+// discard memoized values, e.g. after changing user directories with
+// `SetUserDir`.
+func (r *Resolver) Memoize(config *MemoConfig) *Resolver {
+	if config == nil {
+		config = new(MemoConfig)
+	}
+	return &Resolver{
+		env:  r.env,
+		goos: r.goos,
+		memo: &memo{config: *config, entries: make(map[string]*memoEntry)},
+	}
+}
+
+// Invalidate discards memoized values, so that they are resolved again on
+// the next call. It does nothing if the resolver isn't returned by `Memoize`.
+func (r *Resolver) Invalidate() {
+	if r.memo == nil {
+		return
+	}
+	r.memo.mu.Lock()
+	r.memo.entries = make(map[string]*memoEntry)
+	r.memo.mu.Unlock()
+}
+
+type memo struct {
+	config  MemoConfig
+	mu      sync.Mutex
+	entries map[string]*memoEntry
+}
+
+type memoEntry struct {
+	once  sync.Once
+	value any
+	err   error
+	deps  *recordingEnv
+}
+
+// memoized returns the memoized value for `key`. If there is none, it is
+// resolved by `resolve`, with a resolver that doesn't memoize, and records
+// what the value depends on.
+func (r *Resolver) memoized(key string, resolve func(r *Resolver) (any, error)) (any, error) {
+	m := r.memo
+	for {
+		m.mu.Lock()
+		entry, ok := m.entries[key]
+		if !ok {
+			entry = new(memoEntry)
+			m.entries[key] = entry
+		}
+		m.mu.Unlock()
+
+		resolved := false
+		entry.once.Do(func() {
+			resolved = true
+			env := newRecordingEnv(r.env, r.newLookup(nil, nil).pathEnvVar())
+			entry.value, entry.err = resolve(&Resolver{env: env, goos: r.goos})
+			entry.deps = env
+		})
+		if resolved && entry.err == nil {
+			return entry.value, nil
+		}
+		if entry.err == nil && !(m.config.AutoInvalidate && entry.deps.changed(r.env)) {
+			return entry.value, nil
+		}
+
+		m.mu.Lock()
+		if m.entries[key] == entry {
+			delete(m.entries, key)
+		}
+		m.mu.Unlock()
+		if entry.err != nil {
+			return nil, entry.err
+		}
+	}
+}
+
+// memoKey returns the key of a memoized value. Maps are printed with
+// sorted keys by fmt, hence equal configs have equal keys.
+func memoKey(method string, args ...any) string {
+	return fmt.Sprintf("%s %+v", method, args)
+}
+
+// recordingEnv records the environment variables, the home directory, and
+// the files that are read through it, so that it can be checked whether
+// they have changed afterwards.
+//
+// It isn't safe for concurrent use, since a value is resolved by a single
+// goroutine.
+type recordingEnv struct {
+	Env
+	pathVar  string
+	vars     map[string]string
+	home     *string
+	files    map[string]*fileState
+	symlinks map[string]string
+}
+
+func newRecordingEnv(env Env, pathVar string) *recordingEnv {
+	return &recordingEnv{
+		Env:      env,
+		pathVar:  pathVar,
+		vars:     make(map[string]string),
+		files:    make(map[string]*fileState),
+		symlinks: make(map[string]string),
+	}
+}
+
+func (e *recordingEnv) Getenv(key string) string {
+	value := e.Env.Getenv(key)
+	e.vars[key] = value
+	return value
+}
+
+func (e *recordingEnv) HomeDir() (string, error) {
+	home, err := e.Env.HomeDir()
+	if err == nil {
+		e.home = &home
+	}
+	return home, err
+}
+
+// LookPath searches $PATH, hence the result is resolved again if $PATH changes.
+func (e *recordingEnv) LookPath(file string) (string, error) {
+	e.Getenv(e.pathVar)
+	return e.Env.LookPath(file)
+}
+
+func (e *recordingEnv) ReadFile(name string) ([]byte, error) {
+	// File is stat'ed first, so that a change after the stat is noticed.
+	e.Stat(name)
+	return e.Env.ReadFile(name)
+}
+
+func (e *recordingEnv) Readlink(name string) (string, error) {
+	dest, err := e.Env.Readlink(name)
+	e.symlinks[name] = dest
+	return dest, err
+}
+
+func (e *recordingEnv) Stat(name string) (fs.FileInfo, error) {
+	fi, err := e.Env.Stat(name)
+	e.files[name] = statState(fi, err)
+	return fi, err
+}
+
+// changed reports whether something recorded is different in `env`.
+func (e *recordingEnv) changed(env Env) bool {
+	for key, value := range e.vars {
+		if env.Getenv(key) != value {
+			return true
+		}
+	}
+	if e.home != nil {
+		home, err := env.HomeDir()
+		if err != nil || home != *e.home {
+			return true
+		}
+	}
+	for name, state := range e.files {
+		newState := statState(env.Stat(name))
+		if (state == nil) != (newState == nil) || (state != nil && !state.equal(*newState)) {
+			return true
+		}
+	}
+	for name, dest := range e.symlinks {
+		newDest, _ := env.Readlink(name)
+		if newDest != dest {
+			return true
+		}
+	}
+	return false
+}
+
+// statState returns the state of a file from the result of a stat. If the
+// file doesn't exist, or cannot be stat'ed, nil is returned.
+func statState(fi fs.FileInfo, err error) *fileState {
+	if err != nil {
+		return nil
+	}
+	return &fileState{size: fi.Size(), modTime: fi.ModTime(), mode: fi.Mode()}
+}
+
+// baseEnv returns the environment `env` wraps, so that optional interfaces
+// (e.g. `RuntimeDirEnv`) of the underlying environment can be used.
+func baseEnv(env Env) Env {
+	if e, ok := env.(*recordingEnv); ok {
+		return e.Env
+	}
+	return env
+}
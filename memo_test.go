@@ -0,0 +1,113 @@
+package finddirs
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingEnv counts the environment variables that are read.
+type countingEnv struct {
+	*fakeEnv
+	reads atomic.Int64
+}
+
+func (e *countingEnv) Getenv(key string) string {
+	e.reads.Add(1)
+	return e.fakeEnv.Getenv(key)
+}
+
+func TestMemoize(t *testing.T) {
+	env := &countingEnv{fakeEnv: &fakeEnv{
+		home: "/home/foo",
+		vars: map[string]string{"XDG_CONFIG_HOME": "/cfg"},
+		files: fstest.MapFS{
+			"cfg/user-dirs.dirs": {Data: []byte("XDG_DOWNLOAD_DIR=\"$HOME/dl\"\n")},
+		},
+	}}
+	r, err := NewResolverForOS("linux", env)
+	require.NoError(t, err)
+	r = r.Memoize(nil)
+	config := &AppConfig{Subdir: "foo", EnvVars: map[DirKind]string{KindCache: "FOO_CACHE"}}
+
+	var (
+		wg   sync.WaitGroup
+		dirs = make([]*AppDirs, 8)
+		errs = make([]error, 8)
+	)
+	for i := range dirs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dirs[i], errs[i] = r.AppDirs(false, config)
+		}(i)
+	}
+	wg.Wait()
+	for i := range dirs {
+		require.NoError(t, errs[i])
+		require.Equal(t, "/cfg/foo", dirs[i].ConfigDir)
+	}
+
+	// Resolved once
+	reads := env.reads.Load()
+	d, err := r.AppDirs(false, &AppConfig{Subdir: "foo", EnvVars: map[DirKind]string{KindCache: "FOO_CACHE"}})
+	require.NoError(t, err)
+	require.Equal(t, reads, env.reads.Load())
+
+	// Memoized value cannot be modified.
+	d.ConfigDir = "/modified"
+	d, err = r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, "/cfg/foo", d.ConfigDir)
+
+	// Different arguments are resolved separately.
+	d, err = r.AppDirs(false, &AppConfig{Subdir: "bar"})
+	require.NoError(t, err)
+	require.Equal(t, "/cfg/bar", d.ConfigDir)
+
+	env.vars["XDG_CONFIG_HOME"] = "/config"
+	d, err = r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, "/cfg/foo", d.ConfigDir)
+	r.Invalidate()
+	d, err = r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, "/config/foo", d.ConfigDir)
+}
+
+func TestMemoizeAutoInvalidate(t *testing.T) {
+	env := &fakeEnv{
+		home: "/home/foo",
+		vars: map[string]string{"XDG_CONFIG_HOME": "/cfg"},
+		files: fstest.MapFS{
+			"cfg/user-dirs.dirs": {Data: []byte("XDG_DOWNLOAD_DIR=\"$HOME/dl\"\n")},
+		},
+	}
+	r, err := NewResolverForOS("linux", env)
+	require.NoError(t, err)
+	r = r.Memoize(&MemoConfig{AutoInvalidate: true})
+
+	d, err := r.AppDirs(false, nil)
+	require.NoError(t, err)
+	require.Equal(t, "/cfg", d.ConfigDir)
+	env.vars["XDG_CONFIG_HOME"] = "/config"
+	d, err = r.AppDirs(false, nil)
+	require.NoError(t, err)
+	require.Equal(t, "/config", d.ConfigDir)
+
+	env.vars["XDG_CONFIG_HOME"] = "/cfg"
+	userDirs, err := r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, "/home/foo/dl", userDirs.Downloads)
+	env.files["cfg/user-dirs.dirs"] = &fstest.MapFile{
+		Data:    []byte("XDG_DOWNLOAD_DIR=\"$HOME/Downloads\"\n"),
+		ModTime: time.Now(),
+	}
+	userDirs, err = r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, "/home/foo/Downloads", userDirs.Downloads)
+}
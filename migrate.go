@@ -0,0 +1,377 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Migration moves a file or directory from a legacy location (e.g. ~/.toolname)
+// into one of the directories inside `AppDirs`.
+type Migration struct {
+	// Legacy path. Must be an absolute path, or start with `~/` if
+	// `MigrateConfig.Home` is set.
+	From string
+	// Kind of the directory `From` is moved into.
+	Kind DirKind
+	// Path relative to the directory of `Kind` that `From` is moved to.
+	// If empty, the base name of `From` is used. If it is ".", contents of
+	// `From` are moved into the directory itself.
+	Name string
+}
+
+type MigrationStatus int
+
+const (
+	// Moved to the destination (or would be moved, if `DryRun` is set).
+	MigrationMoved MigrationStatus = iota
+	// Legacy path doesn't exist, or it is already migrated.
+	MigrationSkipped
+	// Destination exists. Conflicting files are left in the legacy location.
+	MigrationConflict
+	// An error occurred. See `MigrationResult.Err`.
+	MigrationFailed
+)
+
+func (s MigrationStatus) String() string {
+	switch s {
+	case MigrationMoved:
+		return "moved"
+	case MigrationSkipped:
+		return "skipped"
+	case MigrationConflict:
+		return "conflict"
+	case MigrationFailed:
+		return "failed"
+	}
+	return fmt.Sprintf("MigrationStatus(%d)", int(s))
+}
+
+type MigrationResult struct {
+	// Legacy path.
+	From string
+	// Destination path.
+	Dest   string
+	Status MigrationStatus
+	// Destination paths that already exist. Only set if `Status` is `MigrationConflict`.
+	Conflicts []string
+	// Only set if `Status` is `MigrationFailed`.
+	Err error
+}
+
+type MigrateConfig struct {
+	// If true, nothing is changed, and the results describe what would be done.
+	DryRun bool
+	// If true, a symbolic link to the destination is left at the legacy path,
+	// so that older versions of the application keep working.
+	Symlink bool
+	// Path of the file that marks the migration as done. If it exists,
+	// `Migrate` does nothing. If empty, .finddirs-migrated inside `StateDir`
+	// is used.
+	Marker string
+	// Home directory `~` at the beginning of `Migration.From` expands to.
+	// It is not taken from the current process, since `AppDirs` might belong
+	// to another user (e.g. ones returned by `RetrieveAppDirsForUser`), or be
+	// system-wide. Use `HomeDir` of the `Env` `AppDirs` are resolved from.
+	// If empty, paths starting with `~` are rejected.
+	Home string
+}
+
+// Migrate moves files and directories from legacy locations into the
+// directories inside `d`. `config` can be nil.
+//
+// Directories are merged: if the destination is an existing directory,
+// entries of the legacy directory are moved into it one by one. Files that
+// already exist at the destination are not overwritten. Instead, they are
+// reported as conflicts and left in place. If the destination is on
+// another filesystem, files are copied and the originals are removed.
+//
+// A failed migration doesn't stop the others. Errors of all failed
+// migrations are returned together, in addition to the results. Once every
+// migration succeeds without a conflict, a marker file (see
+// `MigrateConfig.Marker`) is written, and subsequent calls do nothing and
+// return no results.
+func (d *AppDirs) Migrate(migrations []Migration, config *MigrateConfig) (results []MigrationResult, err error) {
+	if config == nil {
+		config = new(MigrateConfig)
+	}
+	marker := config.Marker
+	if marker == "" {
+		marker = path.Join(d.StateDir, ".finddirs-migrated")
+	}
+	marker = filepath.FromSlash(marker)
+	_, err = os.Stat(marker)
+	if err == nil {
+		return nil, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+
+	var (
+		errs []error
+		done = true
+		log  strings.Builder
+	)
+	for _, m := range migrations {
+		result := d.migrate(m, config)
+		switch result.Status {
+		case MigrationMoved:
+			fmt.Fprintf(&log, "%s -> %s\n", result.From, result.Dest)
+		case MigrationConflict:
+			done = false
+		case MigrationFailed:
+			done = false
+			errs = append(errs, fmt.Errorf("%s: %w", result.From, result.Err))
+		}
+		results = append(results, result)
+	}
+	if len(errs) > 0 {
+		return results, fmt.Errorf("finddirs: %w", errors.Join(errs...))
+	}
+	if !done || config.DryRun {
+		return results, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(marker), 0o700)
+	if err == nil {
+		err = writeFileAtomic(marker, []byte(log.String()), 0o644)
+	}
+	if err != nil {
+		return results, fmt.Errorf("finddirs: %w", err)
+	}
+	return results, nil
+}
+
+func (d *AppDirs) migrate(m Migration, config *MigrateConfig) (result MigrationResult) {
+	fail := func(err error) MigrationResult {
+		result.Status = MigrationFailed
+		result.Err = err
+		return result
+	}
+
+	from := m.From
+	if from == "~" || strings.HasPrefix(filepath.ToSlash(from), "~/") {
+		if config.Home == "" {
+			return fail(fmt.Errorf("legacy path starts with ~, but MigrateConfig.Home is not set: %q", m.From))
+		}
+		from = filepath.Join(config.Home, from[1:])
+	}
+	result.From = path.Clean(filepath.ToSlash(from))
+	if !filepath.IsAbs(from) {
+		return fail(fmt.Errorf("legacy path must be an absolute path: %q", m.From))
+	}
+	from = filepath.FromSlash(result.From)
+	fi, err := os.Lstat(from)
+	if errors.Is(err, fs.ErrNotExist) {
+		result.Status = MigrationSkipped
+		return
+	} else if err != nil {
+		return fail(err)
+	}
+	dir := d.Dir(m.Kind)
+	if dir == "" {
+		return fail(fmt.Errorf("%s directory is not set", m.Kind))
+	}
+	name := m.Name
+	if name == "" {
+		name = path.Base(result.From)
+	}
+	if name != "." {
+		err = checkFileName(name)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	result.Dest = path.Join(dir, filepath.ToSlash(name))
+	dest := filepath.FromSlash(result.Dest)
+
+	// Already migrated, and a symbolic link is left behind.
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		if target, err := filepath.EvalSymlinks(from); err == nil {
+			if destTarget, err := filepath.EvalSymlinks(dest); err == nil && target == destTarget {
+				result.Status = MigrationSkipped
+				return
+			}
+		}
+	}
+
+	if !config.DryRun {
+		_, err = ensureDir(dir, defaultDirMode(m.Kind, d.SystemWide))
+		if err != nil {
+			return fail(err)
+		}
+	}
+	result.Conflicts, err = migratePath(from, dest, config.DryRun)
+	if err != nil {
+		return fail(err)
+	}
+	if len(result.Conflicts) > 0 {
+		result.Status = MigrationConflict
+		return
+	}
+	if config.Symlink && !config.DryRun {
+		err = os.Symlink(dest, from)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	result.Status = MigrationMoved
+	return
+}
+
+// migratePath moves `from` to `dest`. If both are directories, they are
+// merged. Paths that already exist at the destination are returned.
+func migratePath(from, dest string, dryRun bool) (conflicts []string, err error) {
+	fromInfo, err := os.Lstat(from)
+	if err != nil {
+		return nil, err
+	}
+	destInfo, err := os.Lstat(dest)
+	if errors.Is(err, fs.ErrNotExist) {
+		if dryRun {
+			return nil, nil
+		}
+		return nil, movePath(from, dest)
+	} else if err != nil {
+		return nil, err
+	}
+	if !fromInfo.IsDir() || !destInfo.IsDir() {
+		return []string{filepath.ToSlash(dest)}, nil
+	}
+
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		c, err := migratePath(filepath.Join(from, entry.Name()), filepath.Join(dest, entry.Name()), dryRun)
+		conflicts = append(conflicts, c...)
+		if err != nil {
+			return conflicts, err
+		}
+	}
+	if len(conflicts) == 0 && !dryRun {
+		return nil, os.Remove(from)
+	}
+	return conflicts, nil
+}
+
+// movePath renames `from` to `dest`. If `dest` is on another filesystem,
+// `from` is copied and then removed.
+func movePath(from, dest string) error {
+	err := os.MkdirAll(filepath.Dir(dest), 0o700)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(from, dest)
+	if err == nil || !isCrossDeviceError(err) {
+		return err
+	}
+	err = moveByCopy(from, dest)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// moveByCopy copies `from` to `dest`, which must not exist. If copying
+// fails, only what was created by it is removed, never an existing `dest`.
+//
+// Directories are copied into a temporary directory next to `dest`, and
+// renamed to `dest` once they are complete. Files and symbolic links are
+// created exclusively.
+func moveByCopy(from, dest string) error {
+	fi, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return copyPath(from, dest)
+	}
+
+	temp, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp*")
+	if err != nil {
+		return err
+	}
+	err = copyPath(from, temp)
+	if err == nil {
+		// Fails if `dest` has been created by someone else in the meantime,
+		// unless it is an empty directory.
+		err = os.Rename(temp, dest)
+	}
+	if err != nil {
+		os.RemoveAll(temp)
+		return err
+	}
+	return nil
+}
+
+// copyPath copies `from` to `dest` recursively. Modes and symbolic links are
+// preserved. If `from` is a directory, `dest` can be an existing empty
+// directory.
+func copyPath(from, dest string) error {
+	return filepath.WalkDir(from, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		fi, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case fi.IsDir():
+			err = os.Mkdir(target, fi.Mode().Perm())
+			if err != nil && !(p == from && errors.Is(err, fs.ErrExist)) {
+				return err
+			}
+			return os.Chmod(target, fi.Mode().Perm())
+		case fi.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case fi.Mode().IsRegular():
+			return copyFile(p, target, fi.Mode().Perm())
+		}
+		return fmt.Errorf("cannot copy %s: unsupported file type %s", p, fi.Mode().Type())
+	})
+}
+
+func copyFile(from, dest string, perm fs.FileMode) (err error) {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := dst.Close()
+		if err == nil {
+			err = closeErr
+		}
+		// The file is created exclusively, hence it is ours to remove.
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		return err
+	}
+	return dst.Sync()
+}
@@ -0,0 +1,149 @@
+package finddirs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	root := filepath.ToSlash(t.TempDir())
+	legacy := root + "/.foo"
+	require.NoError(t, os.MkdirAll(legacy+"/themes", 0o700))
+	require.NoError(t, os.WriteFile(legacy+"/config.toml", []byte("a = 1"), 0o644))
+	require.NoError(t, os.WriteFile(legacy+"/themes/dark.json", []byte("{}"), 0o644))
+	require.NoError(t, os.WriteFile(legacy+"/history", []byte("ls"), 0o600))
+
+	d := &AppDirs{
+		ConfigDir: root + "/config/foo",
+		StateDir:  root + "/state/foo",
+	}
+	// Conflicting file
+	require.NoError(t, os.MkdirAll(d.ConfigDir+"/themes", 0o700))
+	require.NoError(t, os.WriteFile(d.ConfigDir+"/themes/dark.json", []byte("{}"), 0o644))
+
+	migrations := []Migration{
+		{From: legacy + "/history", Kind: KindState},
+		{From: legacy + "/config.toml", Kind: KindConfig},
+		{From: legacy + "/themes", Kind: KindConfig},
+		{From: root + "/.nonexistent", Kind: KindCache},
+	}
+
+	// Dry run
+	results, err := d.Migrate(migrations, &MigrateConfig{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	require.Equal(t, MigrationMoved, results[0].Status)
+	require.Equal(t, d.StateDir+"/history", results[0].Dest)
+	require.Equal(t, MigrationConflict, results[2].Status)
+	require.Equal(t, []string{d.ConfigDir + "/themes/dark.json"}, results[2].Conflicts)
+	require.Equal(t, MigrationSkipped, results[3].Status)
+	require.FileExists(t, legacy+"/history")
+
+	results, err = d.Migrate(migrations, nil)
+	require.NoError(t, err)
+	require.Equal(t, MigrationMoved, results[0].Status)
+	require.Equal(t, MigrationMoved, results[1].Status)
+	require.Equal(t, MigrationConflict, results[2].Status)
+	require.FileExists(t, d.StateDir+"/history")
+	require.FileExists(t, d.ConfigDir+"/config.toml")
+	require.NoFileExists(t, legacy+"/config.toml")
+	require.FileExists(t, legacy+"/themes/dark.json")
+	require.NoFileExists(t, d.StateDir+"/.finddirs-migrated")
+
+	// Conflict is resolved
+	require.NoError(t, os.Remove(d.ConfigDir+"/themes/dark.json"))
+	results, err = d.Migrate(migrations, nil)
+	require.NoError(t, err)
+	require.Equal(t, MigrationSkipped, results[0].Status)
+	require.Equal(t, MigrationMoved, results[2].Status)
+	require.FileExists(t, d.ConfigDir+"/themes/dark.json")
+	require.NoDirExists(t, legacy+"/themes")
+	require.FileExists(t, d.StateDir+"/.finddirs-migrated")
+
+	// Runs only once
+	results, err = d.Migrate(migrations, nil)
+	require.NoError(t, err)
+	require.Empty(t, results)
+
+	// Invalid migration
+	results, err = d.Migrate([]Migration{{From: "relative", Kind: KindConfig}}, &MigrateConfig{Marker: root + "/marker"})
+	require.Error(t, err)
+	require.Equal(t, MigrationFailed, results[0].Status)
+
+	// `~` is expanded only if the home directory is given.
+	require.NoError(t, os.WriteFile(legacy+"/cookies", nil, 0o600))
+	migrations = []Migration{{From: "~/.foo/cookies", Kind: KindState}}
+	results, err = d.Migrate(migrations, &MigrateConfig{Marker: root + "/marker", DryRun: true})
+	require.Error(t, err)
+	require.Equal(t, MigrationFailed, results[0].Status)
+	results, err = d.Migrate(migrations, &MigrateConfig{Marker: root + "/marker", DryRun: true, Home: root})
+	require.NoError(t, err)
+	require.Equal(t, MigrationMoved, results[0].Status)
+	require.Equal(t, legacy+"/cookies", results[0].From)
+}
+
+func TestMigrateSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("symbolic links are not supported")
+	}
+	root := filepath.ToSlash(t.TempDir())
+	legacy := root + "/.foo"
+	require.NoError(t, os.MkdirAll(legacy, 0o700))
+	require.NoError(t, os.WriteFile(legacy+"/config.toml", nil, 0o644))
+
+	d := &AppDirs{ConfigDir: root + "/config/foo", StateDir: root + "/state/foo"}
+	migrations := []Migration{{From: legacy, Kind: KindConfig, Name: "."}}
+	results, err := d.Migrate(migrations, &MigrateConfig{Symlink: true})
+	require.NoError(t, err)
+	require.Equal(t, MigrationMoved, results[0].Status)
+	require.Equal(t, d.ConfigDir, results[0].Dest)
+	require.FileExists(t, legacy+"/config.toml")
+	target, err := os.Readlink(legacy)
+	require.NoError(t, err)
+	require.Equal(t, d.ConfigDir, filepath.ToSlash(target))
+
+	results, err = d.Migrate(migrations, &MigrateConfig{Marker: root + "/marker"})
+	require.NoError(t, err)
+	require.Equal(t, MigrationSkipped, results[0].Status)
+}
+
+func TestCopyPath(t *testing.T) {
+	from := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "dest")
+	require.NoError(t, os.MkdirAll(filepath.Join(from, "a/b"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "a/b/c"), []byte("c"), 0o600))
+
+	require.NoError(t, copyPath(from, dest))
+	data, err := os.ReadFile(filepath.Join(dest, "a/b/c"))
+	require.NoError(t, err)
+	require.Equal(t, "c", string(data))
+}
+
+func TestMoveByCopy(t *testing.T) {
+	from := t.TempDir()
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "dest")
+	require.NoError(t, os.WriteFile(filepath.Join(from, "a"), []byte("a"), 0o600))
+
+	// Created by someone else in the meantime
+	require.NoError(t, os.Mkdir(dest, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "b"), []byte("b"), 0o600))
+	require.Error(t, moveByCopy(from, dest))
+	require.FileExists(t, filepath.Join(dest, "b"))
+	entries, err := os.ReadDir(parent)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	require.Error(t, moveByCopy(filepath.Join(from, "a"), filepath.Join(dest, "b")))
+	data, err := os.ReadFile(filepath.Join(dest, "b"))
+	require.NoError(t, err)
+	require.Equal(t, "b", string(data))
+
+	require.NoError(t, os.RemoveAll(dest))
+	require.NoError(t, moveByCopy(from, dest))
+	require.FileExists(t, filepath.Join(dest, "a"))
+}
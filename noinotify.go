@@ -0,0 +1,7 @@
+//go:build !linux
+
+package finddirs
+
+// newNotifier returns nil, since there is no notifier on this system.
+// Directories are polled instead.
+func newNotifier() (notifier, error) { return nil, nil }
@@ -0,0 +1,9 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package finddirs
+
+import "os"
+
+func lockFile(f *os.File, exclusive, wait bool) error { return ErrOSNotSupportedLock }
+
+func unlockFile(f *os.File) error { return ErrOSNotSupportedLock }
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package finddirs
+
+import "io/fs"
+
+// Whether file owners and Unix permissions are available on this system.
+const ownersSupported = false
+
+// fileOwner returns the user ID of the owner of the file `fi` describes.
+func fileOwner(fi fs.FileInfo) (uid int, ok bool) { return -1, false }
@@ -0,0 +1,7 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !windows
+
+package finddirs
+
+// freeSpace returns the number of bytes available on the filesystem `dir`
+// resides in. `ok` is false if it cannot be determined.
+func freeSpace(dir string) (free int64, ok bool) { return 0, false }
@@ -0,0 +1,20 @@
+//go:build unix
+
+package finddirs
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Whether file owners and Unix permissions are available on this system.
+const ownersSupported = true
+
+// fileOwner returns the user ID of the owner of the file `fi` describes.
+func fileOwner(fi fs.FileInfo) (uid int, ok bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, false
+	}
+	return int(st.Uid), true
+}
@@ -1,79 +1,126 @@
-//go:build plan9
-
 package finddirs
 
 import (
-	"os"
 	"path"
-
-	"github.com/mitchellh/go-homedir"
+	"runtime"
 )
 
-func desktopDir() (string, error) {
+// plan9Platform retrieves directories as Plan 9 lays them out.
+type plan9Platform struct{ *lookup }
+
+func (l plan9Platform) setUserDirs(dirs map[string]string) error { return ErrOSNotSupportedSetUserDirs }
+
+func (l plan9Platform) desktopDir() (string, error) {
 	return "", ErrOSNotSupportedUserDirs
 }
 
-func downloadsDir() (string, error) {
+func (l plan9Platform) downloadsDir() (string, error) {
 	return "", ErrOSNotSupportedUserDirs
 }
 
-func documentsDir() (string, error) {
+func (l plan9Platform) documentsDir() (string, error) {
 	return "", ErrOSNotSupportedUserDirs
 }
 
-func picturesDir() (string, error) {
+func (l plan9Platform) picturesDir() (string, error) {
 	return "", ErrOSNotSupportedUserDirs
 }
 
-func videosDir() (string, error) {
+func (l plan9Platform) videosDir() (string, error) {
 	return "", ErrOSNotSupportedUserDirs
 }
 
-func musicDir() (string, error) {
+func (l plan9Platform) musicDir() (string, error) {
 	return "", ErrOSNotSupportedUserDirs
 }
 
-func fontsDirs() ([]string, error) {
+func (l plan9Platform) fontsDirs() ([]string, error) {
 	return nil, ErrOSNotSupportedUserDirs
 }
 
-func templatesDir() (string, error) {
+func (l plan9Platform) templatesDir() (string, error) {
 	return "", ErrOSNotSupportedUserDirs
 }
 
-func publicShareDir() (string, error) {
+func (l plan9Platform) publicShareDir() (string, error) {
 	return "", ErrOSNotSupportedUserDirs
 }
 
-func (c *AppConfig) subdirPlatformSpecific() string { return c.SubdirPlan9 }
+func (l plan9Platform) subdirPlatformSpecific() string { return l.config.SubdirPlan9 }
 
-func (c *AppConfig) configDirSystem() (string, error) { return "/lib", nil }
+// Environment variables that are validated by `ValidateXDGEnv`.
+func (l plan9Platform) xdgEnvVars() []string { return nil }
 
-func (c *AppConfig) configDirLocal() (string, error) {
-	home, err := homedir.Dir()
+func (l plan9Platform) configDirSystem() (string, error) { return "/lib", nil }
+
+func (l plan9Platform) configDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "lib"), nil
 }
 
-func (c *AppConfig) stateDirSystem() (string, error) { return "/lib", nil }
+func (l plan9Platform) stateDirSystem() (string, error) { return "/lib", nil }
 
-func (c *AppConfig) stateDirLocal() (string, error) {
-	os.UserCacheDir()
-	home, err := homedir.Dir()
+func (l plan9Platform) stateDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "lib"), nil
 }
 
-func (c *AppConfig) cacheDirSystem() (string, error) { return "/lib/cache", nil }
+func (l plan9Platform) cacheDirSystem() (string, error) { return "/lib/cache", nil }
 
-func (c *AppConfig) cacheDirLocal() (string, error) {
-	home, err := homedir.Dir()
+func (l plan9Platform) cacheDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
 	return path.Join(home, "lib/cache"), nil
 }
+
+func (l plan9Platform) dataDirSystem() (string, error) { return "/lib", nil }
+
+func (l plan9Platform) dataDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(home, "lib"), nil
+}
+
+// /tmp is private to the user's namespace on Plan 9.
+func (l plan9Platform) runtimeDirSystem() (string, error) { return "/tmp", nil }
+
+func (l plan9Platform) runtimeDirLocal() (string, error) { return "/tmp", nil }
+
+// System-wide search directories other than `configDirSystem`.
+func (l plan9Platform) configSearchDirsSystem() ([]string, error) { return nil, nil }
+
+// System-wide search directories other than `dataDirSystem`.
+func (l plan9Platform) dataSearchDirsSystem() ([]string, error) { return nil, nil }
+
+func (l plan9Platform) pathEnvVar() string { return "path" }
+
+// Entries of $path are separated by NUL, since it is a list in rc.
+func (l plan9Platform) pathListSeparator() byte { return 0 }
+
+func (l plan9Platform) caseInsensitivePaths() bool { return false }
+
+// /bin is a union directory that has /$objtype/bin bound to it.
+func (l plan9Platform) binDirSystem() (string, error) { return "/bin", nil }
+
+func (l plan9Platform) binDirLocal() (string, error) {
+	home, err := l.env.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	// $objtype is the architecture of the machine, e.g. amd64.
+	objtype := l.env.Getenv("objtype")
+	if objtype == "" {
+		objtype = runtime.GOARCH
+	}
+	return path.Join(home, "bin", objtype), nil
+}
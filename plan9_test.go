@@ -14,6 +14,7 @@ func TestPlan9AppDirsSystem(t *testing.T) {
 		Subdir:      "foo/bar",
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(true, config)
 	require.NoError(t, err)
@@ -21,6 +22,7 @@ func TestPlan9AppDirsSystem(t *testing.T) {
 	require.Equal(t, "/lib/foo/bar", d.ConfigDir)
 	require.Equal(t, "/lib/foo/bar/state", d.StateDir)
 	require.Equal(t, "/lib/cache/foo/bar", d.CacheDir)
+	require.Equal(t, "/lib/foo/bar/data", d.DataDir)
 }
 
 func TestPlan9AppDirsLocal(t *testing.T) {
@@ -28,6 +30,7 @@ func TestPlan9AppDirsLocal(t *testing.T) {
 		Subdir:      "foo/bar",
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -37,6 +40,7 @@ func TestPlan9AppDirsLocal(t *testing.T) {
 	require.Equal(t, home+"/lib/foo/bar", d.ConfigDir)
 	require.Equal(t, home+"/lib/foo/bar/state", d.StateDir)
 	require.Equal(t, home+"/lib/cache/foo/bar", d.CacheDir)
+	require.Equal(t, home+"/lib/foo/bar/data", d.DataDir)
 }
 
 func TestPlan9AppDirsSubdirPlan9(t *testing.T) {
@@ -45,6 +49,7 @@ func TestPlan9AppDirsSubdirPlan9(t *testing.T) {
 		SubdirPlan9: "zoo/zar",
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(true, config)
 	require.NoError(t, err)
@@ -52,6 +57,7 @@ func TestPlan9AppDirsSubdirPlan9(t *testing.T) {
 	require.Equal(t, "/lib/zoo/zar", d.ConfigDir)
 	require.Equal(t, "/lib/zoo/zar/state", d.StateDir)
 	require.Equal(t, "/lib/cache/zoo/zar", d.CacheDir)
+	require.Equal(t, "/lib/zoo/zar/data", d.DataDir)
 
 	d, err = RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -61,9 +67,23 @@ func TestPlan9AppDirsSubdirPlan9(t *testing.T) {
 	require.Equal(t, home+"/lib/zoo/zar", d.ConfigDir)
 	require.Equal(t, home+"/lib/zoo/zar/state", d.StateDir)
 	require.Equal(t, home+"/lib/cache/zoo/zar", d.CacheDir)
+	require.Equal(t, home+"/lib/zoo/zar/data", d.DataDir)
 }
 
 func TestPlan9UserDirs(t *testing.T) {
 	_, err := RetrieveUserDirs()
 	require.Error(t, err)
 }
+
+func TestPlan9SearchDirs(t *testing.T) {
+	config := &AppConfig{Subdir: "foo/bar"}
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+
+	dirs, err := RetrieveConfigSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/lib/foo/bar", "/lib/foo/bar"}, dirs)
+	dirs, err = RetrieveDataSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/lib/foo/bar", "/lib/foo/bar"}, dirs)
+}
@@ -0,0 +1,76 @@
+package finddirs
+
+// platform retrieves directories as a particular operating system lays
+// them out. Implementations don't depend on the system we're running on,
+// and read everything through `Env`, so that directories of any supported
+// system can be retrieved on any system.
+type platform interface {
+	desktopDir() (string, error)
+	downloadsDir() (string, error)
+	documentsDir() (string, error)
+	picturesDir() (string, error)
+	videosDir() (string, error)
+	musicDir() (string, error)
+	fontsDirs() ([]string, error)
+	templatesDir() (string, error)
+	publicShareDir() (string, error)
+	setUserDirs(dirs map[string]string) error
+
+	subdirPlatformSpecific() string
+	xdgEnvVars() []string
+
+	configDirSystem() (string, error)
+	configDirLocal() (string, error)
+	stateDirSystem() (string, error)
+	stateDirLocal() (string, error)
+	cacheDirSystem() (string, error)
+	cacheDirLocal() (string, error)
+	dataDirSystem() (string, error)
+	dataDirLocal() (string, error)
+	runtimeDirSystem() (string, error)
+	runtimeDirLocal() (string, error)
+	configSearchDirsSystem() ([]string, error)
+	dataSearchDirsSystem() ([]string, error)
+
+	// Name of the environment variable that lists directories to look for
+	// executables in.
+	pathEnvVar() string
+	// Separator of the entries of the environment variable `pathEnvVar`
+	// returns.
+	pathListSeparator() byte
+	// Whether paths are case-insensitive by default.
+	caseInsensitivePaths() bool
+	binDirSystem() (string, error)
+	binDirLocal() (string, error)
+}
+
+// Values of GOOS that are supported by `NewResolverForOS`.
+var supportedOSes = map[string]bool{
+	"aix":       true,
+	"android":   true,
+	"darwin":    true,
+	"dragonfly": true,
+	"freebsd":   true,
+	"hurd":      true,
+	"illumos":   true,
+	"ios":       true,
+	"linux":     true,
+	"netbsd":    true,
+	"openbsd":   true,
+	"plan9":     true,
+	"solaris":   true,
+	"windows":   true,
+	"zos":       true,
+}
+
+func newPlatform(goos string, l *lookup) platform {
+	switch goos {
+	case "windows":
+		return windowsPlatform{l}
+	case "darwin", "ios":
+		return darwinPlatform{lookup: l, ios: goos == "ios"}
+	case "plan9":
+		return plan9Platform{l}
+	}
+	return unixPlatform{l}
+}
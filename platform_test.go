@@ -0,0 +1,148 @@
+package finddirs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlatformWindows(t *testing.T) {
+	r, err := NewResolverForOS("windows", StaticEnv{
+		Home: `C:\Users\foo`,
+		Vars: map[string]string{
+			"USERPROFILE":  `C:\Users\foo`,
+			"APPDATA":      `C:\Users\foo\AppData\Roaming`,
+			"LOCALAPPDATA": `D:\Local`,
+			"ProgramData":  `C:\ProgramData`,
+			"TEMP":         `C:\Users\foo\AppData\Local\Temp`,
+		},
+	})
+	require.NoError(t, err)
+	config := &AppConfig{
+		Subdir:        "foo/bar",
+		SubdirWindows: "Foo",
+		SubdirState:   "state",
+		SubdirCache:   "cache",
+		SubdirData:    "data",
+	}
+
+	d, err := r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, "D:/Local/Foo", d.ConfigDir)
+	require.Equal(t, "D:/Local/Foo/state", d.StateDir)
+	require.Equal(t, "D:/Local/Foo/cache", d.CacheDir)
+	require.Equal(t, "D:/Local/Foo/data", d.DataDir)
+	require.Equal(t, "C:/Users/foo/AppData/Local/Temp/Foo", d.RuntimeDir)
+
+	config.UseRoaming = true
+	d, err = r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, "C:/Users/foo/AppData/Roaming/Foo", d.ConfigDir)
+
+	d, err = r.AppDirs(true, config)
+	require.NoError(t, err)
+	require.Equal(t, "C:/ProgramData/Foo", d.ConfigDir)
+	require.Equal(t, "C:/ProgramData/Foo/state", d.StateDir)
+
+	u, err := r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, "C:/Users/foo/Desktop", u.Desktop)
+	require.Equal(t, "C:/Users/foo/Downloads", u.Downloads)
+	require.Equal(t, "C:/Users/foo/Videos", u.Videos)
+	require.Equal(t, "C:/Users/foo/AppData/Roaming/Microsoft/Windows/Templates", u.Templates)
+	require.Equal(t, "C:/Users/Public", u.PublicShare)
+	require.Equal(t, []string{"C:/Windows/Fonts", "D:/Local/Microsoft/Windows/Fonts"}, u.Fonts)
+
+	dir, err := r.BinDir(false, nil)
+	require.NoError(t, err)
+	require.Equal(t, "D:/Local/Programs", dir)
+	dir, err = r.BinDir(true, nil)
+	require.NoError(t, err)
+	require.Equal(t, "C:/Program Files", dir)
+}
+
+func TestPlatformDarwin(t *testing.T) {
+	r, err := NewResolverForOS("darwin", StaticEnv{Home: "/Users/foo"})
+	require.NoError(t, err)
+	config := &AppConfig{Subdir: "foo", SubdirDarwinIOS: "com.example.foo", SubdirCache: "cache"}
+
+	d, err := r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, "/Users/foo/Library/Application Support/com.example.foo", d.ConfigDir)
+	require.Equal(t, "/Users/foo/Library/Caches/com.example.foo", d.CacheDir)
+	require.Equal(t, "/tmp/com.example.foo", d.RuntimeDir)
+
+	u, err := r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, "/Users/foo/Movies", u.Videos)
+
+	r, err = NewResolverForOS("ios", StaticEnv{Home: "/var/mobile"})
+	require.NoError(t, err)
+	_, err = r.AppDirs(true, config)
+	require.ErrorIs(t, err, ErrOSNotSupportedAppDirsSystemIOS)
+	_, err = r.UserDirs(nil)
+	require.ErrorIs(t, err, ErrOSNotSupportedUserDirs)
+}
+
+func TestPlatformPlan9(t *testing.T) {
+	r, err := NewResolverForOS("plan9", StaticEnv{Home: "/usr/glenda", Vars: map[string]string{"objtype": "386"}})
+	require.NoError(t, err)
+
+	d, err := r.AppDirs(false, &AppConfig{Subdir: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "/usr/glenda/lib/foo", d.ConfigDir)
+	require.Equal(t, "/usr/glenda/lib/cache/foo", d.CacheDir)
+
+	dir, err := r.BinDir(false, nil)
+	require.NoError(t, err)
+	require.Equal(t, "/usr/glenda/bin/386", dir)
+}
+
+func TestPlatformUnix(t *testing.T) {
+	r, err := NewResolverForOS("freebsd", StaticEnv{
+		Home: "/home/foo",
+		Vars: map[string]string{"XDG_CONFIG_HOME": "/cfg", "XDG_RUNTIME_DIR": "/run/user/1000"},
+	})
+	require.NoError(t, err)
+
+	d, err := r.AppDirs(false, &AppConfig{Subdir: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "/cfg/foo", d.ConfigDir)
+	require.Equal(t, "/home/foo/.local/state/foo", d.StateDir)
+	require.Equal(t, "/run/user/1000/foo", d.RuntimeDir)
+
+	u, err := r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, "/home/foo/Downloads", u.Downloads)
+
+	_, err = NewResolverForOS("js", nil)
+	require.ErrorIs(t, err, ErrOSNotSupported)
+}
+
+func TestPlatformIsInPath(t *testing.T) {
+	r, err := NewResolverForOS("windows", StaticEnv{
+		Home: `C:\Users\foo`,
+		Vars: map[string]string{"PATH": `C:\Windows;"C:\Program Files;x\bin";c:\users\foo\AppData\Local\Programs\`},
+	})
+	require.NoError(t, err)
+	require.True(t, r.IsInPath(`C:\Windows`))
+	require.True(t, r.IsInPath("C:/Program Files;x/bin"))
+	require.True(t, r.IsInPath("C:/Users/foo/AppData/Local/Programs"))
+	require.False(t, r.IsInPath("C:/Program Files"))
+
+	r, err = NewResolverForOS("plan9", StaticEnv{
+		Home: "/usr/foo",
+		Vars: map[string]string{"path": "/bin\x00/usr/foo/bin/amd64"},
+	})
+	require.NoError(t, err)
+	require.True(t, r.IsInPath("/usr/foo/bin/amd64"))
+	require.False(t, r.IsInPath("/usr/foo/bin"))
+
+	r, err = NewResolverForOS("linux", StaticEnv{
+		Home: "/home/foo",
+		Vars: map[string]string{"PATH": `/usr/bin:/home/foo/.local/bin/`},
+	})
+	require.NoError(t, err)
+	require.True(t, r.IsInPath("/home/foo/.local/bin"))
+	require.False(t, r.IsInPath("/home/foo/.local/BIN"))
+}
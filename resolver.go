@@ -0,0 +1,217 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// Env is the environment directories are resolved from. Every environment
+// variable, home directory, executable and file that affects the returned
+// paths is read through it.
+//
+// Features that need more than reading the environment (e.g. writing files)
+// are only available if it implements the optional interfaces below.
+//
+// Use `OSEnv` for the environment of the current process. Other
+// implementations can be used to resolve directories of a different
+// environment, or to simulate one in tests.
+type Env interface {
+	// Getenv returns the value of the environment variable `key`,
+	// or empty string if it is unset.
+	Getenv(key string) string
+	// HomeDir returns the home directory of the current user.
+	HomeDir() (string, error)
+	// LookPath searches for the executable `file` in $PATH.
+	LookPath(file string) (string, error)
+	// ReadFile reads the file `name`.
+	ReadFile(name string) ([]byte, error)
+	// Readlink returns the destination of the symbolic link `name`.
+	Readlink(name string) (string, error)
+	// Stat returns the file info of `name`. Symbolic links are followed.
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// Optional interfaces an `Env` can implement. `OSEnv` implements all of them
+// (`KnownFolderEnv` only on Windows), and `UserEnv` all of them except
+// `KnownFolderEnv` and `CommandEnv`. Other environments can implement them to
+// behave like `OSEnv`.
+
+// UserIDEnv is implemented by environments that know the user ID of their
+// user. On Unix, without the user ID, there is no fallback for
+// $XDG_RUNTIME_DIR, hence the runtime directory is empty unless it is set.
+type UserIDEnv interface {
+	// UserID returns the user ID of the user.
+	UserID() int
+}
+
+// RuntimeDirEnv is implemented by environments that are backed by a real
+// filesystem. In other environments, `EnsureRuntimeDir` neither creates
+// nor checks the runtime directory.
+type RuntimeDirEnv interface {
+	// CheckRuntimeDir checks whether `dir` is a directory that is owned by
+	// the user and only they can access, and returns `ErrRuntimeDirUnsafe`
+	// otherwise. If `create` is true, `dir` is created first if it doesn't
+	// exist.
+	CheckRuntimeDir(dir string, create bool) error
+}
+
+// FileWriterEnv is implemented by environments that files can be written
+// to. In other environments, `SetUserDir` and `SaveUserDirs` return
+// `ErrEnvReadOnly`.
+type FileWriterEnv interface {
+	// WriteFile writes `data` to `name` atomically. If the directory of
+	// `name` doesn't exist, it is created with mode 0700.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// KnownFolderEnv is implemented by environments that can retrieve paths of
+// Windows known folders. In other environments, paths of known folders are
+// derived from environment variables (%USERPROFILE%, %APPDATA%,
+// %LOCALAPPDATA%, %ProgramData% and so on).
+type KnownFolderEnv interface {
+	// KnownFolderPath returns the path of the known folder `folder`.
+	KnownFolderPath(folder KnownFolder) (string, error)
+}
+
+// CommandEnv is implemented by environments that can run executables
+// `LookPath` returns. In other environments, no executable is run (e.g.
+// xdg-user-dir for `XDGUserDirFallback`).
+type CommandEnv interface {
+	// RunCommand runs the executable `name` with `args`, and returns what it
+	// has written to its standard output.
+	RunCommand(name string, args ...string) ([]byte, error)
+}
+
+// OSEnv is the environment of the current process.
+type OSEnv struct{}
+
+func (OSEnv) Getenv(key string) string              { return os.Getenv(key) }
+func (OSEnv) HomeDir() (string, error)              { return homedir.Dir() }
+func (OSEnv) LookPath(file string) (string, error)  { return exec.LookPath(file) }
+func (OSEnv) ReadFile(name string) ([]byte, error)  { return os.ReadFile(name) }
+func (OSEnv) Readlink(name string) (string, error)  { return os.Readlink(name) }
+func (OSEnv) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+func (OSEnv) UserID() int                           { return os.Getuid() }
+
+func (OSEnv) RunCommand(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
+
+// StaticEnv is an `Env` made of fixed values. It has no files, symbolic
+// links or executables. Use it with `NewResolverForOS` to retrieve the
+// directories another system would use, given its environment variables.
+//
+// Since it has no user ID either, the runtime directory on Unix is empty
+// unless $XDG_RUNTIME_DIR is set.
+type StaticEnv struct {
+	// Environment variables.
+	Vars map[string]string
+	// Home directory. If empty, `HomeDir` returns an error.
+	Home string
+}
+
+func (e StaticEnv) Getenv(key string) string { return e.Vars[key] }
+
+func (e StaticEnv) HomeDir() (string, error) {
+	if e.Home == "" {
+		return "", errors.New("home directory is not set")
+	}
+	return e.Home, nil
+}
+
+func (e StaticEnv) LookPath(file string) (string, error) {
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+func (e StaticEnv) ReadFile(name string) ([]byte, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (e StaticEnv) Readlink(name string) (string, error) {
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+}
+
+func (e StaticEnv) Stat(name string) (fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// Resolver retrieves directories from an `Env`. Package-level functions
+// (e.g. `RetrieveAppDirs`) use a resolver with `OSEnv`.
+//
+// Resolvers are safe for concurrent use. By default, every call resolves
+// directories again; use `Memoize` to resolve them once.
+type Resolver struct {
+	env  Env
+	goos string
+	// Nil unless the resolver is returned by `Memoize`.
+	memo *memo
+}
+
+// NewResolver returns a resolver that retrieves directories of the system
+// we're running on from `env`. If `env` is nil, `OSEnv` is used.
+func NewResolver(env Env) *Resolver {
+	if env == nil {
+		env = OSEnv{}
+	}
+	return &Resolver{env: env, goos: runtime.GOOS}
+}
+
+// NewResolverForOS returns a resolver that retrieves directories as the
+// operating system `goos` (a value of GOOS, e.g. "windows") would, from
+// `env`. This works on any system, e.g. Windows directories can be
+// retrieved on Linux with a `StaticEnv`:
+//
+//	r, _ := NewResolverForOS("windows", StaticEnv{Home: `C:\Users\foo`})
+//
+// If `env` is nil, `OSEnv` is used. If `goos` is not supported,
+// `ErrOSNotSupported` is returned.
+func NewResolverForOS(goos string, env Env) (*Resolver, error) {
+	if !supportedOSes[goos] {
+		return nil, fmt.Errorf("finddirs: %w: %s", ErrOSNotSupported, goos)
+	}
+	r := NewResolver(env)
+	r.goos = goos
+	return r, nil
+}
+
+var defaultResolver = NewResolver(OSEnv{})
+
+// lookup holds the state of a single call of a `Resolver` method.
+type lookup struct {
+	platform
+	env        Env
+	config     *AppConfig
+	userConfig *UserConfig
+
+	// Whether running on Termux. Populated on first access.
+	isTermux      bool
+	termuxChecked bool
+	// Whether running inside Flatpak. Populated on first access.
+	isFlatpak      bool
+	flatpakChecked bool
+
+	// Entries of user-dirs.dirs. Populated on first access.
+	userDirs       map[string]string
+	userDirsLoaded bool
+	// If non-empty, user directories are retrieved by running
+	// xdg-user-dir at this path instead.
+	userDirCommand string
+}
+
+func (r *Resolver) newLookup(config *AppConfig, userConfig *UserConfig) *lookup {
+	if config == nil {
+		config = new(AppConfig)
+	}
+	if userConfig == nil {
+		userConfig = new(UserConfig)
+	}
+	l := &lookup{env: r.env, config: config, userConfig: userConfig}
+	l.platform = newPlatform(r.goos, l)
+	return l
+}
@@ -0,0 +1,122 @@
+package finddirs
+
+import (
+	"io/fs"
+	"os/exec"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEnv is an in-memory environment for hermetic tests.
+type fakeEnv struct {
+	vars        map[string]string
+	home        string
+	files       fstest.MapFS
+	symlinks    map[string]string
+	executables map[string]string
+}
+
+func (e *fakeEnv) Getenv(key string) string { return e.vars[key] }
+func (e *fakeEnv) HomeDir() (string, error) { return e.home, nil }
+
+func (e *fakeEnv) LookPath(file string) (string, error) {
+	if path, ok := e.executables[file]; ok {
+		return path, nil
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+func (e *fakeEnv) ReadFile(name string) ([]byte, error) {
+	return e.files.ReadFile(strings.TrimPrefix(name, "/"))
+}
+
+func (e *fakeEnv) Readlink(name string) (string, error) {
+	if target, ok := e.symlinks[name]; ok {
+		return target, nil
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+}
+
+func (e *fakeEnv) Stat(name string) (fs.FileInfo, error) {
+	return e.files.Stat(strings.TrimPrefix(name, "/"))
+}
+
+func TestResolverEnvOverride(t *testing.T) {
+	r := NewResolver(&fakeEnv{
+		home: "/home/foo",
+		vars: map[string]string{
+			"MYAPP_CONFIG_DIR":  "/srv/myapp/config",
+			"MYAPP_STATE_DIR":   "/srv/myapp/state",
+			"MYAPP_CACHE_DIR":   "/srv/myapp/cache",
+			"MYAPP_DATA_DIR":    "/srv/myapp/data",
+			"MYAPP_RUNTIME_DIR": "/srv/myapp/run",
+		},
+	})
+	d, err := r.AppDirs(false, &AppConfig{Subdir: "myapp", EnvPrefix: "MYAPP", RequireEnv: true})
+	require.NoError(t, err)
+
+	require.Equal(t, "/srv/myapp/config", d.ConfigDir)
+	require.Equal(t, "/srv/myapp/state", d.StateDir)
+	require.Equal(t, "/srv/myapp/cache", d.CacheDir)
+	require.Equal(t, "/srv/myapp/data", d.DataDir)
+	require.Equal(t, "/srv/myapp/run", d.RuntimeDir)
+}
+
+// capableEnv implements the optional interfaces of `Env`.
+type capableEnv struct {
+	*fakeEnv
+	uid      int
+	commands [][]string
+	written  map[string]string
+}
+
+func (e *capableEnv) UserID() int { return e.uid }
+
+func (e *capableEnv) RunCommand(name string, args ...string) ([]byte, error) {
+	e.commands = append(e.commands, append([]string{name}, args...))
+	return []byte("/home/foo/Dl\n"), nil
+}
+
+func (e *capableEnv) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	e.written[name] = string(data)
+	return nil
+}
+
+func TestResolverOptionalInterfaces(t *testing.T) {
+	env := &capableEnv{
+		fakeEnv: &fakeEnv{
+			home: "/home/foo",
+			vars: map[string]string{"TMPDIR": "/var/tmp"},
+			files: fstest.MapFS{
+				"home/foo/.config/user-dirs.dirs": {Data: []byte("invalid\n")},
+			},
+			executables: map[string]string{"xdg-user-dir": "/usr/bin/xdg-user-dir"},
+		},
+		uid:     1000,
+		written: make(map[string]string),
+	}
+	r, err := NewResolverForOS("linux", env)
+	require.NoError(t, err)
+
+	d, err := r.AppDirs(false, &AppConfig{Subdir: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "/var/tmp/runtime-1000/foo", d.RuntimeDir)
+
+	u, err := r.UserDirs(&UserConfig{XDGUserDirFallback: true})
+	require.NoError(t, err)
+	require.Equal(t, "/home/foo/Dl", u.Downloads)
+	require.Contains(t, env.commands, []string{"/usr/bin/xdg-user-dir", "DOWNLOAD"})
+
+	require.NoError(t, r.SetUserDir(UserDirMusic, "/home/foo/Songs"))
+	require.Contains(t, env.written["/home/foo/.config/user-dirs.dirs"], `XDG_MUSIC_DIR="$HOME/Songs"`)
+
+	// Without `CommandEnv`, the error is returned instead.
+	r, err = NewResolverForOS("linux", env.fakeEnv)
+	require.NoError(t, err)
+	_, err = r.UserDirs(&UserConfig{XDGUserDirFallback: true})
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+}
@@ -0,0 +1,67 @@
+//go:build unix
+
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+func (OSEnv) CheckRuntimeDir(dir string, create bool) error {
+	if !create {
+		return checkRuntimeDir(dir, os.Stat, os.Getuid())
+	}
+	err := os.Mkdir(dir, 0o700)
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	// Someone else might have created it before us. Don't follow symlinks.
+	return checkRuntimeDir(dir, os.Lstat, os.Getuid())
+}
+
+// The directory is checked for the user of the environment. If it is
+// created, its owner is changed to the user, which requires privileges.
+func (e *UserEnv) CheckRuntimeDir(dir string, create bool) error {
+	if !create {
+		return checkRuntimeDir(dir, os.Stat, e.UID)
+	}
+	err := os.Mkdir(dir, 0o700)
+	if err == nil {
+		err = os.Lchown(dir, e.UID, e.GID)
+		if err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return checkRuntimeDir(dir, os.Lstat, e.UID)
+}
+
+// checkRuntimeDir checks whether `dir` is owned by the user `uid` and
+// accessible only by them, as XDG Base Directory Specification requires.
+// If `dir` is owned by the user, but its mode is not 0700, it is changed.
+func checkRuntimeDir(dir string, stat func(name string) (fs.FileInfo, error), uid int) error {
+	fi, err := stat(dir)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("%w: %s is a symbolic link", ErrRuntimeDirUnsafe, dir)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrRuntimeDirUnsafe, dir)
+	}
+	owner, ok := fileOwner(fi)
+	if !ok || owner != uid {
+		return fmt.Errorf("%w: %s is not owned by the user (UID %d)", ErrRuntimeDirUnsafe, dir, uid)
+	}
+	if fi.Mode().Perm() != 0o700 {
+		err = os.Chmod(dir, 0o700)
+		if err != nil {
+			return fmt.Errorf("%w: %s has mode %s: %v", ErrRuntimeDirUnsafe, dir, fi.Mode().Perm(), err)
+		}
+	}
+	return nil
+}
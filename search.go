@@ -0,0 +1,248 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// RetrieveConfigSearchDirs returns the list of directories to look for
+// config files in, ordered by precedence: local config directory comes
+// first, system-wide config directories follow it. Application directory
+// is appended to each entry, just like `RetrieveAppDirs` does. Local and
+// system-wide config directories are the ones `RetrieveAppDirs` returns,
+// hence environment variables that override them (see `EnvPrefix`) apply.
+//
+// On Unix, system-wide config directories are the entries of
+// $XDG_CONFIG_DIRS (/etc/xdg if it is unset), followed by /etc.
+// On other systems, only the system-wide config directory is used.
+// On iOS, there is no system-wide directory.
+//
+// Entries that are empty, relative, or duplicate are removed from the list.
+func RetrieveConfigSearchDirs(config *AppConfig) (dirs []string, err error) {
+	return defaultResolver.ConfigSearchDirs(config)
+}
+
+// ConfigSearchDirs is the same as `RetrieveConfigSearchDirs`, but it uses
+// the environment of the resolver.
+func (r *Resolver) ConfigSearchDirs(config *AppConfig) (dirs []string, err error) {
+	if r.memo != nil {
+		if config == nil {
+			config = new(AppConfig)
+		}
+		v, err := r.memoized(memoKey("ConfigSearchDirs", *config), func(r *Resolver) (any, error) {
+			return r.ConfigSearchDirs(config)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return append([]string(nil), v.([]string)...), nil
+	}
+
+	l := r.newLookup(config, nil)
+	dirs, err = l.searchDirs(KindConfig, l.configSearchDirsSystem, l.appendConfigSubdir)
+	if err != nil {
+		err = fmt.Errorf("finddirs: %w", err)
+	}
+	return
+}
+
+// RetrieveDataSearchDirs returns the list of directories to look for
+// data files in, ordered by precedence: local data directory comes
+// first, system-wide data directories follow it. Application directory
+// is appended to each entry, just like `RetrieveAppDirs` does. Local and
+// system-wide data directories are the ones `RetrieveAppDirs` returns.
+//
+// On Unix, system-wide data directories are the entries of $XDG_DATA_DIRS
+// (/usr/local/share:/usr/share if it is unset), followed by the system-wide
+// data directory. On other systems, only the system-wide data directory is used.
+// On iOS, there is no system-wide directory.
+//
+// Entries that are empty, relative, or duplicate are removed from the list.
+func RetrieveDataSearchDirs(config *AppConfig) (dirs []string, err error) {
+	return defaultResolver.DataSearchDirs(config)
+}
+
+// DataSearchDirs is the same as `RetrieveDataSearchDirs`, but it uses
+// the environment of the resolver.
+func (r *Resolver) DataSearchDirs(config *AppConfig) (dirs []string, err error) {
+	if r.memo != nil {
+		if config == nil {
+			config = new(AppConfig)
+		}
+		v, err := r.memoized(memoKey("DataSearchDirs", *config), func(r *Resolver) (any, error) {
+			return r.DataSearchDirs(config)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return append([]string(nil), v.([]string)...), nil
+	}
+
+	l := r.newLookup(config, nil)
+	dirs, err = l.searchDirs(KindData, l.dataSearchDirsSystem, l.appendSubdir)
+	if err != nil {
+		err = fmt.Errorf("finddirs: %w", err)
+	}
+	return
+}
+
+// searchDirs returns the local directory of `kind`, entries of
+// `searchDirsSystem` (with the application directory appended), and the
+// system-wide directory of `kind`. Local and system-wide directories are
+// the ones `AppDirs` returns.
+func (l *lookup) searchDirs(
+	kind DirKind,
+	searchDirsSystem func() ([]string, error),
+	appendSubdir func(dir string) string,
+) ([]string, error) {
+	localDir, ok, err := l.resolveDir(kind, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, l.config.missingEnvError([]DirKind{kind})
+	}
+	dirs := []string{localDir}
+
+	systemDirs, err := searchDirsSystem()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range systemDirs {
+		dirs = appendUnique(dirs, filepath.ToSlash(appendSubdir(dir)))
+	}
+
+	systemDir, _, err := l.resolveDir(kind, true)
+	if errors.Is(err, ErrOSNotSupportedAppDirsSystemIOS) || errors.Is(err, ErrAppDirsSystemFlatpak) {
+		return dirs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return appendUnique(dirs, systemDir), nil
+}
+
+// FindConfigFile looks for `name` inside the directories `RetrieveConfigSearchDirs`
+// returns, and returns the path of the first one that exists.
+//
+// `name` is relative to the config directory, and it may contain slashes
+// (e.g. "themes/dark.json"). If `extensions` are given, instead of `name`, `name`
+// with each extension appended is tried in given order inside each directory:
+//
+//	FindConfigFile(config, "config", ".json", ".toml")
+//
+// If no file is found, `ErrFileNotFound` is returned.
+func FindConfigFile(config *AppConfig, name string, extensions ...string) (string, error) {
+	return defaultResolver.FindConfigFile(config, name, extensions...)
+}
+
+// FindConfigFiles is the same as `FindConfigFile`, but it returns all files that
+// exist, ordered by precedence. If no file is found, empty slice and no error are returned.
+func FindConfigFiles(config *AppConfig, name string, extensions ...string) ([]string, error) {
+	return defaultResolver.FindConfigFiles(config, name, extensions...)
+}
+
+// FindDataFile looks for `name` inside the directories `RetrieveDataSearchDirs`
+// returns, and returns the path of the first one that exists.
+//
+// See `FindConfigFile` for the usage of `name` and `extensions`.
+//
+// If no file is found, `ErrFileNotFound` is returned.
+func FindDataFile(config *AppConfig, name string, extensions ...string) (string, error) {
+	return defaultResolver.FindDataFile(config, name, extensions...)
+}
+
+// FindDataFiles is the same as `FindDataFile`, but it returns all files that
+// exist, ordered by precedence. If no file is found, empty slice and no error are returned.
+func FindDataFiles(config *AppConfig, name string, extensions ...string) ([]string, error) {
+	return defaultResolver.FindDataFiles(config, name, extensions...)
+}
+
+// FindConfigFile is the same as the package-level `FindConfigFile`, but it
+// uses the environment of the resolver.
+func (r *Resolver) FindConfigFile(config *AppConfig, name string, extensions ...string) (string, error) {
+	files, err := r.findFiles(config, r.ConfigSearchDirs, true, name, extensions)
+	if err != nil {
+		return "", err
+	}
+	return files[0], nil
+}
+
+// FindConfigFiles is the same as the package-level `FindConfigFiles`, but it
+// uses the environment of the resolver.
+func (r *Resolver) FindConfigFiles(config *AppConfig, name string, extensions ...string) ([]string, error) {
+	return r.findFiles(config, r.ConfigSearchDirs, false, name, extensions)
+}
+
+// FindDataFile is the same as the package-level `FindDataFile`, but it
+// uses the environment of the resolver.
+func (r *Resolver) FindDataFile(config *AppConfig, name string, extensions ...string) (string, error) {
+	files, err := r.findFiles(config, r.DataSearchDirs, true, name, extensions)
+	if err != nil {
+		return "", err
+	}
+	return files[0], nil
+}
+
+// FindDataFiles is the same as the package-level `FindDataFiles`, but it
+// uses the environment of the resolver.
+func (r *Resolver) FindDataFiles(config *AppConfig, name string, extensions ...string) ([]string, error) {
+	return r.findFiles(config, r.DataSearchDirs, false, name, extensions)
+}
+
+func (r *Resolver) findFiles(config *AppConfig,
+	searchDirs func(config *AppConfig) ([]string, error),
+	first bool,
+	name string,
+	extensions []string,
+) (files []string, err error) {
+	err = checkFileName(name)
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	dirs, err := searchDirs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{name}
+	if len(extensions) > 0 {
+		names = names[:0]
+		for _, ext := range extensions {
+			names = append(names, name+ext)
+		}
+	}
+
+	files = []string{}
+	for _, dir := range dirs {
+		for _, name := range names {
+			file := path.Join(dir, name)
+			_, err := r.env.Stat(filepath.FromSlash(file))
+			if err != nil {
+				continue
+			}
+			files = append(files, file)
+			if first {
+				return files, nil
+			}
+		}
+	}
+	if first {
+		return nil, fmt.Errorf("finddirs: %w: %s", ErrFileNotFound, name)
+	}
+	return files, nil
+}
+
+// checkFileName returns `ErrInvalidFileName` if `name` is not a relative
+// path, or it would escape the directory it is joined with.
+func checkFileName(name string) error {
+	name = filepath.ToSlash(name)
+	clean := path.Clean(name)
+	if name == "" || clean == "." || path.IsAbs(clean) || filepath.IsAbs(filepath.FromSlash(name)) ||
+		clean == ".." || strings.HasPrefix(clean, "../") || filepath.VolumeName(filepath.FromSlash(name)) != "" {
+		return fmt.Errorf("%w: %q", ErrInvalidFileName, name)
+	}
+	return nil
+}
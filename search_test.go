@@ -0,0 +1,43 @@
+package finddirs
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFileName(t *testing.T) {
+	for _, name := range []string{"config.json", "themes/dark.json", "a/../b", ".hidden"} {
+		require.NoError(t, checkFileName(name), name)
+	}
+	for _, name := range []string{"", ".", "..", "../x", "a/../../x", "/etc/passwd"} {
+		require.ErrorIs(t, checkFileName(name), ErrInvalidFileName, name)
+	}
+}
+
+func TestFindConfigFileEnvOverride(t *testing.T) {
+	r, err := NewResolverForOS("linux", &fakeEnv{
+		home: "/home/foo",
+		vars: map[string]string{"MYAPP_CONFIG_DIR": "/srv/myapp/config"},
+		files: fstest.MapFS{
+			"srv/myapp/config/config.json":       {},
+			"home/foo/.config/myapp/config.json": {},
+			"etc/xdg/myapp/config.json":          {},
+		},
+	})
+	require.NoError(t, err)
+	config := &AppConfig{Subdir: "myapp", EnvPrefix: "MYAPP"}
+
+	dirs, err := r.ConfigSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/srv/myapp/config", "/etc/xdg/myapp"}, dirs)
+
+	file, err := r.FindConfigFile(config, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, "/srv/myapp/config/config.json", file)
+
+	files, err := r.FindConfigFiles(config, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, []string{"/srv/myapp/config/config.json", "/etc/xdg/myapp/config.json"}, files)
+}
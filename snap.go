@@ -0,0 +1,117 @@
+package finddirs
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// SnapInfo describes the snap the application runs inside. Paths are the
+// values of the environment variables snapd sets.
+type SnapInfo struct {
+	// Name and revision of the snap ($SNAP_NAME and $SNAP_REVISION).
+	Name     string
+	Revision string
+
+	// Home directory of the user outside of the snap ($SNAP_REAL_HOME).
+	// $HOME points to $SNAP_USER_DATA inside strictly confined snaps.
+	RealHome string
+	// Per-user directories of the snap. $SNAP_USER_DATA is specific to the
+	// revision, whereas $SNAP_USER_COMMON is kept across refreshes.
+	UserData   string
+	UserCommon string
+	// System-wide directories of the snap. $SNAP_DATA is specific to the
+	// revision, whereas $SNAP_COMMON is kept across refreshes.
+	Data   string
+	Common string
+}
+
+// RetrieveSnapInfo returns information about the snap the current process
+// runs inside. Snaps are detected through $SNAP_NAME. If not running inside
+// a snap, nil is returned. If one of the directories is not an absolute
+// path, an error of type `*InvalidEnvError` is returned.
+//
+// To keep config, state and cache directories across refreshes, set
+// `SnapCommon` in `AppConfig`. To retrieve user directories of the real
+// home directory, set `SnapRealHome` in `UserConfig`.
+func RetrieveSnapInfo() (*SnapInfo, error) {
+	return defaultResolver.SnapInfo()
+}
+
+// SnapInfo is the same as `RetrieveSnapInfo`, but it uses the environment
+// of the resolver. On systems other than Unix, nil is returned.
+func (r *Resolver) SnapInfo() (*SnapInfo, error) {
+	l := r.newLookup(nil, nil)
+	p, ok := l.platform.(unixPlatform)
+	if !ok || !p.snap() {
+		return nil, nil
+	}
+	info := &SnapInfo{
+		Name:     l.env.Getenv("SNAP_NAME"),
+		Revision: l.env.Getenv("SNAP_REVISION"),
+	}
+	for _, v := range []struct {
+		name string
+		dir  *string
+	}{
+		{"SNAP_REAL_HOME", &info.RealHome},
+		{"SNAP_USER_DATA", &info.UserData},
+		{"SNAP_USER_COMMON", &info.UserCommon},
+		{"SNAP_DATA", &info.Data},
+		{"SNAP_COMMON", &info.Common},
+	} {
+		value := l.env.Getenv(v.name)
+		if value != "" && !path.IsAbs(filepath.ToSlash(value)) {
+			return nil, fmt.Errorf("finddirs: %w", &InvalidEnvError{Name: v.name, Value: value, Reason: "path is relative"})
+		}
+		*v.dir = value
+	}
+	return info, nil
+}
+
+// snap reports whether running inside a snap.
+func (l unixPlatform) snap() bool { return l.env.Getenv("SNAP_NAME") != "" }
+
+// snapCommonDir returns the directory for `kind` inside the directory of
+// the snap that is kept across refreshes, if `SnapCommon` is set, and
+// running inside a snap. It is laid out just like the home directory (or
+// the root directory if `systemWide` is true).
+func (l unixPlatform) snapCommonDir(kind DirKind, systemWide bool) (dir string, ok bool) {
+	if !l.config.SnapCommon || !l.snap() {
+		return "", false
+	}
+	common := "SNAP_USER_COMMON"
+	if systemWide {
+		common = "SNAP_COMMON"
+	}
+	dir = filepath.ToSlash(l.env.Getenv(common))
+	if !path.IsAbs(dir) {
+		return "", false
+	}
+
+	var subdirs map[DirKind]string
+	if systemWide {
+		subdirs = map[DirKind]string{KindConfig: "etc", KindState: "var/lib", KindCache: "var/cache"}
+	} else {
+		subdirs = map[DirKind]string{KindConfig: ".config", KindState: ".local/state", KindCache: ".cache"}
+	}
+	subdir, ok := subdirs[kind]
+	if !ok {
+		return "", false
+	}
+	return path.Join(dir, subdir), true
+}
+
+// userHome returns the home directory user directories are relative to.
+// If `SnapRealHome` is set, and running inside a snap, it is the real home
+// directory.
+func (l unixPlatform) userHome() (home string, snapRealHome bool, err error) {
+	if l.userConfig.SnapRealHome && l.snap() {
+		home = filepath.ToSlash(l.env.Getenv("SNAP_REAL_HOME"))
+		if path.IsAbs(home) {
+			return home, true, nil
+		}
+	}
+	home, err = l.env.HomeDir()
+	return home, false, err
+}
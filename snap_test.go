@@ -0,0 +1,86 @@
+package finddirs
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnap(t *testing.T) {
+	snapHome := "/home/foo/snap/foo/42"
+	env := &fakeEnv{
+		home: snapHome,
+		vars: map[string]string{
+			"HOME":             snapHome,
+			"SNAP":             "/snap/foo/42",
+			"SNAP_NAME":        "foo",
+			"SNAP_REVISION":    "42",
+			"SNAP_REAL_HOME":   "/home/foo",
+			"SNAP_USER_DATA":   snapHome,
+			"SNAP_USER_COMMON": "/home/foo/snap/foo/common",
+			"SNAP_DATA":        "/var/snap/foo/42",
+			"SNAP_COMMON":      "/var/snap/foo/common",
+			"XDG_CONFIG_HOME":  snapHome + "/.config",
+		},
+		files: fstest.MapFS{
+			"home/foo/.config/user-dirs.dirs": {Data: []byte("XDG_DOWNLOAD_DIR=\"$HOME/dl\"\n")},
+		},
+	}
+	r, err := NewResolverForOS("linux", env)
+	require.NoError(t, err)
+
+	info, err := r.SnapInfo()
+	require.NoError(t, err)
+	require.Equal(t, &SnapInfo{
+		Name:       "foo",
+		Revision:   "42",
+		RealHome:   "/home/foo",
+		UserData:   snapHome,
+		UserCommon: "/home/foo/snap/foo/common",
+		Data:       "/var/snap/foo/42",
+		Common:     "/var/snap/foo/common",
+	}, info)
+
+	// Revision-specific by default
+	d, err := r.AppDirs(false, &AppConfig{Subdir: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, snapHome+"/.config/foo", d.ConfigDir)
+
+	config := &AppConfig{Subdir: "foo", SnapCommon: true}
+	d, err = r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, "/home/foo/snap/foo/common/.config/foo", d.ConfigDir)
+	require.Equal(t, "/home/foo/snap/foo/common/.local/state/foo", d.StateDir)
+	require.Equal(t, "/home/foo/snap/foo/common/.cache/foo", d.CacheDir)
+	require.Equal(t, snapHome+"/.local/share/foo", d.DataDir)
+
+	d, err = r.AppDirs(true, config)
+	require.NoError(t, err)
+	require.Equal(t, "/var/snap/foo/common/etc/foo", d.ConfigDir)
+	require.Equal(t, "/var/snap/foo/common/var/lib/foo", d.StateDir)
+	require.Equal(t, "/var/snap/foo/common/var/cache/foo", d.CacheDir)
+
+	u, err := r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, snapHome+"/Downloads", u.Downloads)
+	u, err = r.UserDirs(&UserConfig{SnapRealHome: true})
+	require.NoError(t, err)
+	require.Equal(t, "/home/foo/dl", u.Downloads)
+
+	env.vars["SNAP_DATA"] = "var/snap/foo/42"
+	_, err = r.SnapInfo()
+	var invalid *InvalidEnvError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, "SNAP_DATA", invalid.Name)
+	env.vars["SNAP_DATA"] = "/var/snap/foo/42"
+
+	// Not inside a snap
+	delete(env.vars, "SNAP_NAME")
+	info, err = r.SnapInfo()
+	require.NoError(t, err)
+	require.Nil(t, info)
+	d, err = r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, snapHome+"/.config/foo", d.ConfigDir)
+}
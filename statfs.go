@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || linux
+
+package finddirs
+
+import "golang.org/x/sys/unix"
+
+// freeSpace returns the number of bytes available to unprivileged users on
+// the filesystem `dir` resides in. `ok` is false if it cannot be determined.
+func freeSpace(dir string) (free int64, ok bool) {
+	var st unix.Statfs_t
+	if unix.Statfs(dir, &st) != nil {
+		return 0, false
+	}
+	return int64(st.Bavail) * int64(st.Bsize), true
+}
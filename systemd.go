@@ -0,0 +1,72 @@
+package finddirs
+
+import (
+	"path"
+	"strings"
+)
+
+// Environment variables systemd sets for services that have
+// `ConfigurationDirectory=`, `StateDirectory=`, `CacheDirectory=`, or
+// `RuntimeDirectory=` in their unit files.
+var systemdEnvVars = map[DirKind]string{
+	KindConfig:  "CONFIGURATION_DIRECTORY",
+	KindState:   "STATE_DIRECTORY",
+	KindCache:   "CACHE_DIRECTORY",
+	KindRuntime: "RUNTIME_DIRECTORY",
+}
+
+// systemdDir returns the directory systemd has set up for `kind`, if
+// running as a systemd service. Since the variables can list several
+// directories (one for each entry in the unit file), the one that ends
+// with the application directory is returned. Processes that are started
+// by services inherit the variables; requiring the application directory
+// to match prevents them from using the directories of the service. Hence
+// if `Subdir` is empty, they are not used at all.
+//
+// With `DynamicUser=`, directories are inside /var/lib/private (and so
+// on), and the variables point to symbolic links to them. Both are accepted.
+func (l *lookup) systemdDir(kind DirKind) (string, bool) {
+	name, ok := systemdEnvVars[kind]
+	if !ok {
+		return "", false
+	}
+	return l.systemdDirOf(name)
+}
+
+// RetrieveSystemdLogsDir returns the logs directory systemd has set up for
+// the service with `LogsDirectory=` ($LOGS_DIRECTORY), since there is no
+// log directory in `AppDirs`. Just like with other systemd directories, the
+// entry that ends with the application directory is returned. If there is
+// none, or not running on Unix, `ok` is false. `config` can be nil.
+//
+// `Subdir` must be set (e.g. to the name in `LogsDirectory=`). Otherwise
+// `ok` is always false, even if $LOGS_DIRECTORY has a single entry, since
+// it might have been inherited from another service.
+func RetrieveSystemdLogsDir(config *AppConfig) (dir string, ok bool) {
+	return defaultResolver.SystemdLogsDir(config)
+}
+
+// SystemdLogsDir is the same as `RetrieveSystemdLogsDir`, but it uses the
+// environment of the resolver.
+func (r *Resolver) SystemdLogsDir(config *AppConfig) (dir string, ok bool) {
+	return r.newLookup(config, nil).systemdDirOf("LOGS_DIRECTORY")
+}
+
+// systemdDirOf returns the entry of the systemd environment variable `name`
+// that ends with the application directory.
+func (l *lookup) systemdDirOf(name string) (string, bool) {
+	if _, ok := l.platform.(unixPlatform); !ok {
+		return "", false
+	}
+	subdir := l.subdir()
+	if subdir == "" {
+		return "", false
+	}
+	suffix := "/" + path.Clean(subdir)
+	for _, dir := range splitXDGDirs(l.env.Getenv(name)) {
+		if strings.HasSuffix(dir, suffix) {
+			return dir, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,63 @@
+package finddirs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdDirs(t *testing.T) {
+	env := &fakeEnv{
+		home: "/root",
+		vars: map[string]string{
+			"CONFIGURATION_DIRECTORY": "/etc/foo",
+			// DynamicUser=yes
+			"STATE_DIRECTORY":   "/var/lib/private/bar:/var/lib/private/foo",
+			"CACHE_DIRECTORY":   "/var/cache/foo",
+			"RUNTIME_DIRECTORY": "/run/foo",
+			"LOGS_DIRECTORY":    "/var/log/private/foo",
+		},
+	}
+	r, err := NewResolverForOS("linux", env)
+	require.NoError(t, err)
+
+	d, err := r.AppDirs(true, &AppConfig{Subdir: "foo", SubdirState: "state"})
+	require.NoError(t, err)
+	require.Equal(t, "/etc/foo", d.ConfigDir)
+	require.Equal(t, "/var/lib/private/foo", d.StateDir)
+	require.Equal(t, "/var/cache/foo", d.CacheDir)
+	require.Equal(t, "/usr/share/foo", d.DataDir)
+	require.Equal(t, "/run/foo", d.RuntimeDir)
+
+	// Directories of another service are not used.
+	d, err = r.AppDirs(true, &AppConfig{Subdir: "baz"})
+	require.NoError(t, err)
+	require.Equal(t, "/var/lib/baz", d.StateDir)
+	_, ok := r.SystemdLogsDir(&AppConfig{Subdir: "baz"})
+	require.False(t, ok)
+
+	dir, ok := r.SystemdLogsDir(&AppConfig{Subdir: "foo"})
+	require.True(t, ok)
+	require.Equal(t, "/var/log/private/foo", dir)
+
+	// Without `Subdir`, even single entries are not used.
+	d, err = r.AppDirs(true, nil)
+	require.NoError(t, err)
+	require.Equal(t, "/etc", d.ConfigDir)
+	_, ok = r.SystemdLogsDir(nil)
+	require.False(t, ok)
+
+	// Local directories are not affected.
+	d, err = r.AppDirs(false, &AppConfig{Subdir: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "/root/.local/state/foo", d.StateDir)
+
+	// Other systems are not affected.
+	r, err = NewResolverForOS("darwin", env)
+	require.NoError(t, err)
+	d, err = r.AppDirs(true, &AppConfig{Subdir: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "/Library/Application Support/foo", d.StateDir)
+	_, ok = r.SystemdLogsDir(&AppConfig{Subdir: "foo"})
+	require.False(t, ok)
+}
@@ -1,191 +1,302 @@
-//go:build unix && !darwin && !ios
-
 package finddirs
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
-
-	"github.com/mitchellh/go-homedir"
 )
 
-var runningOnTermux = func() bool {
-	_, err := exec.LookPath("termux-setup-storage")
-	return err == nil
-}()
+// unixPlatform retrieves directories as Unix based systems (except macOS and
+// iOS) lay them out, following XDG Base Directory Specification.
+type unixPlatform struct{ *lookup }
 
-func getValueFromXDG(key string) (string, error) {
-	// We don't directly parse ~/.config/user-dirs.dirs — it is a bash script.
-	// Instead, we source it, and echo out the particular variable.
-	output, err := exec.Command("bash", "-c", "source ${XDG_CONFIG_HOME:-~/.config}/user-dirs.dirs && echo ${XDG_"+key+"_DIR}").CombinedOutput()
-	if err == nil {
-		return strings.Trim(string(output), "\n"), nil
+// termux reports whether running on Termux.
+func (l unixPlatform) termux() bool {
+	if !l.termuxChecked {
+		_, err := l.env.LookPath("termux-setup-storage")
+		l.isTermux = err == nil
+		l.termuxChecked = true
 	}
+	return l.isTermux
+}
 
-	// Try running xdg-user-dir as a fallback
-	output, err = exec.Command("xdg-user-dir", key).CombinedOutput()
+func (l unixPlatform) xdgConfigHome() (string, error) {
+	configHome, err := l.xdgEnv("XDG_CONFIG_HOME")
 	if err != nil {
 		return "", err
 	}
-	return strings.Trim(string(output), "\n"), nil
+	if configHome == "" {
+		home, err := l.env.HomeDir()
+		if err != nil {
+			return "", err
+		}
+		return path.Join(home, ".config"), nil
+	}
+	return configHome, nil
 }
 
-func readTermuxSymlink(subdir string) (string, error) {
-	home, err := homedir.Dir()
-	if err != nil {
-		return "", err
+func (l unixPlatform) xdgConfigDirs() []string {
+	dirs := splitXDGDirs(l.env.Getenv("XDG_CONFIG_DIRS"))
+	if len(dirs) == 0 {
+		return []string{"/etc/xdg"}
 	}
-	return os.Readlink(filepath.Join(home, "storage", subdir))
+	return dirs
 }
 
-func desktopDir() (dir string, err error) {
-	if runningOnTermux {
-		return "", nil
+// readFirst reads the first file named `name` that exists inside `dirs`.
+// If none of them exists, an error satisfying errors.Is(err, fs.ErrNotExist)
+// is returned.
+func (l unixPlatform) readFirst(dirs []string, name string) (filePath string, data []byte, err error) {
+	err = fs.ErrNotExist
+	for _, dir := range dirs {
+		filePath = path.Join(dir, name)
+		data, err = l.env.ReadFile(filePath)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return
+		}
 	}
+	return "", nil, err
+}
 
-	home, err := homedir.Dir()
+// load reads and parses user-dirs.dirs once.
+//
+// If user-dirs.dirs doesn't exist, defaults are used just like
+// xdg-user-dirs-update would: they are read from user-dirs.defaults, or
+// built-in ones are used if it doesn't exist either. If xdg-user-dirs is
+// disabled in user-dirs.conf, defaults are not used, and all directories
+// are left unset.
+func (l unixPlatform) load() error {
+	if l.userDirsLoaded {
+		return nil
+	}
+	home, configHome, err := l.userDirsHome()
 	if err != nil {
-		return "", err
+		return err
+	}
+	filePath := path.Join(configHome, userDirsFileName)
+	data, err := l.env.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) && l.flatpak() {
+		// $XDG_CONFIG_HOME points inside the sandbox. Try the one of the
+		// host, which is reachable if the home directory is shared.
+		if hostPath := path.Join(home, ".config", userDirsFileName); hostPath != filePath {
+			filePath = hostPath
+			data, err = l.env.ReadFile(filePath)
+		}
 	}
-	dir, err = getValueFromXDG("DESKTOP")
-	if err != nil {
-		return "", err
+	if err == nil {
+		l.userDirs, err = parseUserDirs(filePath, data, home)
+	} else if errors.Is(err, fs.ErrNotExist) {
+		l.userDirs, err = l.defaultUserDirs(configHome, home)
 	}
-	dir = filepath.Clean(dir)
-	home = filepath.Clean(home)
-	if home == dir {
-		return "", nil
+	if err != nil {
+		if !l.userConfig.XDGUserDirFallback {
+			return err
+		}
+		if _, ok := baseEnv(l.env).(CommandEnv); !ok {
+			return err
+		}
+		command, lookErr := l.env.LookPath("xdg-user-dir")
+		if lookErr != nil {
+			return err
+		}
+		l.userDirCommand = command
 	}
-	return
+	l.userDirsLoaded = true
+	return nil
 }
 
-func downloadsDir() (dir string, err error) {
-	if runningOnTermux {
-		return readTermuxSymlink("downloads")
-	}
-
-	home, err := homedir.Dir()
+// userDirsHome returns the home directory user directories are relative
+// to, and the config directory user-dirs.dirs is inside.
+func (l unixPlatform) userDirsHome() (home, configHome string, err error) {
+	home, snapRealHome, err := l.userHome()
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	dir, err = getValueFromXDG("DOWNLOAD")
-	if err != nil {
-		return "", err
-	}
-	dir = filepath.Clean(dir)
-	home = filepath.Clean(home)
-	if home == dir {
-		return "", nil
+	if snapRealHome {
+		// $XDG_CONFIG_HOME points inside the snap.
+		return home, path.Join(home, ".config"), nil
 	}
-	return
+	configHome, err = l.xdgConfigHome()
+	return home, configHome, err
 }
 
-func documentsDir() (dir string, err error) {
-	if runningOnTermux {
-		shared, err := readTermuxSymlink("shared")
-		if err != nil {
-			return "", err
-		}
-		return path.Join(shared, "Documents"), nil
+func (l unixPlatform) defaultUserDirs(configHome, home string) (map[string]string, error) {
+	configDirs := l.xdgConfigDirs()
+
+	_, data, err := l.readFirst(append([]string{configHome}, configDirs...), userDirsConfFileName)
+	if err == nil && !parseUserDirsConf(data) {
+		return map[string]string{}, nil
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
 	}
 
-	home, err := homedir.Dir()
+	_, data, err = l.readFirst(configDirs, userDirsDefaultsFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return builtinUserDirs(home), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseUserDirsDefaults(data, home), nil
+}
+
+func (l unixPlatform) value(key string) (string, error) {
+	err := l.load()
 	if err != nil {
 		return "", err
 	}
-	dir, err = getValueFromXDG("DOCUMENTS")
+	if l.userDirCommand == "" {
+		return l.userDirs[key], nil
+	}
+	output, err := baseEnv(l.env).(CommandEnv).RunCommand(l.userDirCommand, key)
 	if err != nil {
 		return "", err
 	}
-	dir = filepath.Clean(dir)
-	home = filepath.Clean(home)
-	if home == dir {
-		return "", nil
-	}
-	return
+	return strings.TrimSuffix(string(output), "\n"), nil
 }
 
-func picturesDir() (dir string, err error) {
-	if runningOnTermux {
-		return readTermuxSymlink("pictures")
-	}
-
-	home, err := homedir.Dir()
+// xdgDir returns the directory `key` points to. If the directory is unset,
+// or it is the home directory, empty string is returned.
+func (l unixPlatform) xdgDir(key string) (string, error) {
+	home, _, err := l.userHome()
 	if err != nil {
 		return "", err
 	}
-	dir, err = getValueFromXDG("PICTURES")
-	if err != nil {
+	dir, err := l.value(key)
+	if err != nil || dir == "" {
 		return "", err
 	}
 	dir = filepath.Clean(dir)
-	home = filepath.Clean(home)
-	if home == dir {
+	if filepath.Clean(home) == dir {
 		return "", nil
 	}
-	return
+	return dir, nil
 }
 
-func videosDir() (dir string, err error) {
-	if runningOnTermux {
-		return readTermuxSymlink("movies")
+func (l unixPlatform) setUserDirs(dirs map[string]string) error {
+	// Inside Flatpak, user-dirs.dirs of the host cannot be written.
+	if l.termux() || l.flatpak() {
+		return ErrOSNotSupportedSetUserDirs
 	}
-
-	home, err := homedir.Dir()
+	writer, ok := baseEnv(l.env).(FileWriterEnv)
+	if !ok {
+		return ErrEnvReadOnly
+	}
+	home, configHome, err := l.userDirsHome()
 	if err != nil {
-		return "", err
+		return err
+	}
+	home = filepath.ToSlash(home)
+	// `dirs` belongs to the caller.
+	normalized := make(map[string]string, len(dirs))
+	for name, dir := range dirs {
+		if !isShellName(name) {
+			return fmt.Errorf("invalid user directory name: %q", name)
+		}
+		if dir == "" {
+			dir = home
+		} else if !path.IsAbs(dir) {
+			return fmt.Errorf("user directory %s must be an absolute path: %q", name, dir)
+		}
+		normalized[name] = dir
+	}
+
+	filePath := path.Join(configHome, userDirsFileName)
+	perm := os.FileMode(0o644)
+	data, err := l.env.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Keep the directories that are currently in use.
+		defaults, err := l.defaultUserDirs(configHome, home)
+		if err != nil {
+			return err
+		}
+		data = []byte(userDirsFileHeader)
+		data = updateUserDirs(data, defaults, home)
+	} else if err != nil {
+		return err
+	} else if fi, err := l.env.Stat(filePath); err == nil {
+		perm = fi.Mode().Perm()
 	}
-	dir, err = getValueFromXDG("VIDEOS")
+
+	data = updateUserDirs(data, normalized, home)
+	return writer.WriteFile(filePath, data, perm)
+}
+
+func (l unixPlatform) readTermuxSymlink(subdir string) (string, error) {
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
-	dir = filepath.Clean(dir)
-	home = filepath.Clean(home)
-	if home == dir {
+	return l.env.Readlink(filepath.Join(home, "storage", subdir))
+}
+
+func (l unixPlatform) desktopDir() (string, error) {
+	if l.termux() {
 		return "", nil
 	}
-	return
+	return l.xdgDir(UserDirDesktop)
 }
 
-func musicDir() (dir string, err error) {
-	if runningOnTermux {
-		return readTermuxSymlink("music")
+func (l unixPlatform) downloadsDir() (string, error) {
+	if l.termux() {
+		return l.readTermuxSymlink("downloads")
 	}
+	return l.xdgDir(UserDirDownloads)
+}
 
-	home, err := homedir.Dir()
-	if err != nil {
-		return "", err
+func (l unixPlatform) documentsDir() (string, error) {
+	if l.termux() {
+		shared, err := l.readTermuxSymlink("shared")
+		if err != nil {
+			return "", err
+		}
+		return path.Join(shared, "Documents"), nil
 	}
-	dir, err = getValueFromXDG("MUSIC")
-	if err != nil {
-		return "", err
+	return l.xdgDir(UserDirDocuments)
+}
+
+func (l unixPlatform) picturesDir() (string, error) {
+	if l.termux() {
+		return l.readTermuxSymlink("pictures")
 	}
-	dir = filepath.Clean(dir)
-	home = filepath.Clean(home)
-	if home == dir {
-		return "", nil
+	return l.xdgDir(UserDirPictures)
+}
+
+func (l unixPlatform) videosDir() (string, error) {
+	if l.termux() {
+		return l.readTermuxSymlink("movies")
 	}
-	return
+	return l.xdgDir(UserDirVideos)
+}
+
+func (l unixPlatform) musicDir() (string, error) {
+	if l.termux() {
+		return l.readTermuxSymlink("music")
+	}
+	return l.xdgDir(UserDirMusic)
 }
 
-func fontsDirs() (dirs []string, err error) {
-	if runningOnTermux {
+func (l unixPlatform) fontsDirs() (dirs []string, err error) {
+	if l.termux() {
 		return nil, nil
 	}
 
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return nil, err
 	}
 
 	homeLocalShareFonts := path.Join(home, ".local/share/fonts")
-	xdgDataHome := os.Getenv("XDG_DATA_HOME")
+	xdgDataHome, err := l.xdgEnv("XDG_DATA_HOME")
+	if err != nil {
+		return nil, err
+	}
 
 	// Avoid duplicate paths
-	if xdgDataHome != "" && filepath.Clean(xdgDataHome) != homeLocalShareFonts {
+	if xdgDataHome != "" && path.Join(xdgDataHome, "fonts") != homeLocalShareFonts {
 		dirs = append(dirs, path.Join(xdgDataHome, "fonts"))
 	}
 	dirs = append(dirs,
@@ -197,115 +308,341 @@ func fontsDirs() (dirs []string, err error) {
 	return
 }
 
-func templatesDir() (dir string, err error) {
-	if runningOnTermux {
+func (l unixPlatform) templatesDir() (string, error) {
+	if l.termux() {
 		return "", nil
 	}
+	return l.xdgDir(UserDirTemplates)
+}
 
-	home, err := homedir.Dir()
+func (l unixPlatform) publicShareDir() (string, error) {
+	if l.termux() {
+		return "", nil
+	}
+	return l.xdgDir(UserDirPublicShare)
+}
+
+func (l unixPlatform) subdirPlatformSpecific() string { return l.config.SubdirUnix }
+
+// Environment variables that are validated by `ValidateXDGEnv`.
+func (l unixPlatform) xdgEnvVars() []string {
+	return []string{
+		"XDG_CONFIG_HOME",
+		"XDG_STATE_HOME",
+		"XDG_CACHE_HOME",
+		"XDG_DATA_HOME",
+		"XDG_RUNTIME_DIR",
+		"XDG_BIN_HOME",
+	}
+}
+
+func (l unixPlatform) configDirSystem() (string, error) {
+	if l.flatpak() {
+		return "", ErrAppDirsSystemFlatpak
+	}
+	if dir, ok := l.snapCommonDir(KindConfig, true); ok {
+		return dir, nil
+	}
+	if !l.termux() {
+		return "/etc", nil
+	}
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
-	dir, err = getValueFromXDG("TEMPLATES")
+	return path.Join(home, "../usr/etc"), nil
+}
+
+func (l unixPlatform) configDirLocal() (string, error) {
+	if dir, ok := l.snapCommonDir(KindConfig, false); ok {
+		return dir, nil
+	}
+	dir, err := l.xdgEnv("XDG_CONFIG_HOME")
 	if err != nil {
 		return "", err
 	}
-	dir = filepath.Clean(dir)
-	home = filepath.Clean(home)
-	if home == dir {
-		return "", nil
+	if dir == "" {
+		home, err := l.env.HomeDir()
+		if err != nil {
+			return "", err
+		}
+		return path.Join(home, ".config"), nil
 	}
-	return
+	return dir, nil
 }
 
-func publicShareDir() (dir string, err error) {
-	if runningOnTermux {
-		return "", nil
+func (l unixPlatform) stateDirSystem() (string, error) {
+	if l.flatpak() {
+		return "", ErrAppDirsSystemFlatpak
 	}
-
-	home, err := homedir.Dir()
+	if dir, ok := l.snapCommonDir(KindState, true); ok {
+		return dir, nil
+	}
+	if !l.termux() {
+		return "/var/lib", nil
+	}
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
-	dir, err = getValueFromXDG("PUBLICSHARE")
+	return path.Join(home, "../usr/var/lib"), nil
+}
+
+func (l unixPlatform) stateDirLocal() (string, error) {
+	if dir, ok := l.snapCommonDir(KindState, false); ok {
+		return dir, nil
+	}
+	dir, err := l.xdgEnv("XDG_STATE_HOME")
 	if err != nil {
 		return "", err
 	}
-	dir = filepath.Clean(dir)
-	home = filepath.Clean(home)
-	if home == dir {
-		return "", nil
+	if dir == "" {
+		home, err := l.env.HomeDir()
+		if err != nil {
+			return "", err
+		}
+		return path.Join(home, ".local/state"), nil
 	}
-	return
+	return dir, nil
 }
 
-func (c *AppConfig) subdirPlatformSpecific() string { return c.SubdirUnix }
-
-func (c *AppConfig) configDirSystem() (string, error) {
-	if !runningOnTermux {
-		return "/etc", nil
+func (l unixPlatform) cacheDirSystem() (string, error) {
+	if l.flatpak() {
+		return "", ErrAppDirsSystemFlatpak
+	}
+	if dir, ok := l.snapCommonDir(KindCache, true); ok {
+		return dir, nil
+	}
+	if !l.termux() {
+		return "/var/cache", nil
 	}
-	home, err := homedir.Dir()
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
-	return path.Join(home, "../usr/etc"), nil
+	return path.Join(home, "../usr/var/cache"), nil
 }
 
-func (c *AppConfig) configDirLocal() (string, error) {
-	dir := os.Getenv("XDG_CONFIG_HOME")
+func (l unixPlatform) cacheDirLocal() (string, error) {
+	if dir, ok := l.snapCommonDir(KindCache, false); ok {
+		return dir, nil
+	}
+	dir, err := l.xdgEnv("XDG_CACHE_HOME")
+	if err != nil {
+		return "", err
+	}
 	if dir == "" {
-		home, err := homedir.Dir()
+		home, err := l.env.HomeDir()
 		if err != nil {
 			return "", err
 		}
-		return path.Join(home, ".config"), nil
+		return path.Join(home, ".cache"), nil
 	}
-	return filepath.Clean(dir), nil
+	return dir, nil
 }
 
-func (c *AppConfig) stateDirSystem() (string, error) {
-	if !runningOnTermux {
-		return "/var/lib", nil
+func (l unixPlatform) dataDirSystem() (string, error) {
+	if l.flatpak() {
+		return "", ErrAppDirsSystemFlatpak
 	}
-	home, err := homedir.Dir()
+	if !l.termux() {
+		if l.config.UsrLocal {
+			return "/usr/local/share", nil
+		}
+		return "/usr/share", nil
+	}
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
-	return path.Join(home, "../usr/var/lib"), nil
+	return path.Join(home, "../usr/share"), nil
 }
 
-func (c *AppConfig) stateDirLocal() (string, error) {
-	dir := os.Getenv("XDG_STATE_HOME")
+func (l unixPlatform) dataDirLocal() (string, error) {
+	dir, err := l.xdgEnv("XDG_DATA_HOME")
+	if err != nil {
+		return "", err
+	}
 	if dir == "" {
-		home, err := homedir.Dir()
+		home, err := l.env.HomeDir()
 		if err != nil {
 			return "", err
 		}
-		return path.Join(home, ".local/state"), nil
+		return path.Join(home, ".local/share"), nil
 	}
-	return filepath.Clean(dir), nil
+	return dir, nil
 }
 
-func (c *AppConfig) cacheDirSystem() (string, error) {
-	if !runningOnTermux {
-		return "/var/cache", nil
+func (l unixPlatform) runtimeDirSystem() (string, error) {
+	if l.flatpak() {
+		return "", ErrAppDirsSystemFlatpak
 	}
-	home, err := homedir.Dir()
+	if !l.termux() {
+		return "/run", nil
+	}
+	home, err := l.env.HomeDir()
 	if err != nil {
 		return "", err
 	}
-	return path.Join(home, "../usr/var/cache"), nil
+	return path.Join(home, "../usr/var/run"), nil
 }
 
-func (c *AppConfig) cacheDirLocal() (string, error) {
-	dir := os.Getenv("XDG_CACHE_HOME")
+func (l unixPlatform) runtimeDirLocal() (string, error) {
+	dir, err := l.xdgEnv("XDG_RUNTIME_DIR")
+	if err != nil || dir != "" {
+		return dir, err
+	}
+	dir, uid, err := l.runtimeDirFallback()
+	if err != nil || dir == "" {
+		return "", err
+	}
+	// An unsafe directory only leaves `RuntimeDir` empty, so that the other
+	// directories are still retrieved. `EnsureRuntimeDir` reports why.
+	err = l.verifyRuntimeDir(dir, uid)
+	if errors.Is(err, ErrRuntimeDirUnsafe) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// runtimeDirFallback returns the directory that is used if $XDG_RUNTIME_DIR
+// is unset: a directory inside the temporary directory, just like Qt does.
+// Without the user ID, there is no runtime directory.
+func (l unixPlatform) runtimeDirFallback() (dir string, uid int, err error) {
+	e, ok := baseEnv(l.env).(UserIDEnv)
+	if !ok {
+		return "", -1, nil
+	}
+	// A relative $TMPDIR is ignored, just like relative XDG variables are.
+	tempDir, err := l.xdgEnv("TMPDIR")
+	if err != nil {
+		return "", -1, err
+	}
+	if tempDir == "" {
+		tempDir = "/tmp"
+	}
+	uid = e.UserID()
+	return path.Join(tempDir, fmt.Sprintf("runtime-%d", uid)), uid, nil
+}
+
+// verifyRuntimeDir checks whether the fallback runtime directory is safe to
+// use, without changing anything. Since anyone can create it inside the
+// temporary directory, it must be a directory owned by `uid` that only they
+// can access. A directory that doesn't exist is fine; `EnsureRuntimeDir`
+// creates it.
+func (l unixPlatform) verifyRuntimeDir(dir string, uid int) error {
+	if _, err := l.env.Readlink(dir); err == nil {
+		return fmt.Errorf("%w: %s is a symbolic link", ErrRuntimeDirUnsafe, dir)
+	}
+	fi, err := l.env.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrRuntimeDirUnsafe, dir)
+	}
+	owner, ok := fileOwner(fi)
+	if !ok || owner != uid {
+		return fmt.Errorf("%w: %s is not owned by the user (UID %d)", ErrRuntimeDirUnsafe, dir, uid)
+	}
+	if fi.Mode().Perm() != 0o700 {
+		return fmt.Errorf("%w: %s has mode %s", ErrRuntimeDirUnsafe, dir, fi.Mode().Perm())
+	}
+	return nil
+}
+
+// ensureRuntimeDir checks whether the local runtime directory is safe to
+// use. If it is the fallback directory, it is created first.
+func (l unixPlatform) ensureRuntimeDir() error {
+	checker, ok := baseEnv(l.env).(RuntimeDirEnv)
+	if !ok {
+		return nil
+	}
+	dir, err := l.xdgEnv("XDG_RUNTIME_DIR")
+	if err != nil {
+		return err
+	}
+	create := dir == ""
+	if create {
+		dir, _, err = l.runtimeDirFallback()
+		if err != nil || dir == "" {
+			return err
+		}
+	}
+	return checker.CheckRuntimeDir(filepath.FromSlash(dir), create)
+}
+
+// Entries of $XDG_CONFIG_DIRS.
+func (l unixPlatform) configSearchDirsSystem() ([]string, error) {
+	dirs := splitXDGDirs(l.env.Getenv("XDG_CONFIG_DIRS"))
+	if len(dirs) > 0 {
+		return dirs, nil
+	}
+	if !l.termux() {
+		return []string{"/etc/xdg"}, nil
+	}
+	home, err := l.env.HomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return []string{path.Join(home, "../usr/etc/xdg")}, nil
+}
+
+// Entries of $XDG_DATA_DIRS.
+func (l unixPlatform) dataSearchDirsSystem() ([]string, error) {
+	dirs := splitXDGDirs(l.env.Getenv("XDG_DATA_DIRS"))
+	if len(dirs) > 0 {
+		return dirs, nil
+	}
+	if !l.termux() {
+		return []string{"/usr/local/share", "/usr/share"}, nil
+	}
+	home, err := l.env.HomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return []string{path.Join(home, "../usr/share")}, nil
+}
+
+func (l unixPlatform) pathEnvVar() string { return "PATH" }
+
+func (l unixPlatform) pathListSeparator() byte { return ':' }
+
+func (l unixPlatform) caseInsensitivePaths() bool { return false }
+
+func (l unixPlatform) binDirSystem() (string, error) {
+	if l.flatpak() {
+		return "", ErrAppDirsSystemFlatpak
+	}
+	if !l.termux() {
+		return "/usr/local/bin", nil
+	}
+	prefix := l.env.Getenv("PREFIX")
+	if prefix != "" {
+		return path.Join(filepath.Clean(prefix), "bin"), nil
+	}
+	home, err := l.env.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(home, "../usr/bin"), nil
+}
+
+func (l unixPlatform) binDirLocal() (string, error) {
+	dir, err := l.xdgEnv("XDG_BIN_HOME")
+	if err != nil {
+		return "", err
+	}
 	if dir == "" {
-		home, err := homedir.Dir()
+		home, err := l.env.HomeDir()
 		if err != nil {
 			return "", err
 		}
-		return path.Join(home, ".cache"), nil
+		return path.Join(home, ".local/bin"), nil
 	}
-	return filepath.Clean(dir), nil
+	return dir, nil
 }
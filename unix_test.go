@@ -3,8 +3,12 @@
 package finddirs
 
 import (
+	"fmt"
+	"io/fs"
+	"os"
 	"os/exec"
 	"testing"
+	"testing/fstest"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/stretchr/testify/require"
@@ -15,6 +19,7 @@ func TestUnixAppDirsSystem(t *testing.T) {
 		Subdir:      "foo/bar",
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(true, config)
 	require.NoError(t, err)
@@ -22,6 +27,7 @@ func TestUnixAppDirsSystem(t *testing.T) {
 	require.Equal(t, "/etc/foo/bar", d.ConfigDir)
 	require.Equal(t, "/var/lib/foo/bar", d.StateDir)
 	require.Equal(t, "/var/cache/foo/bar", d.CacheDir)
+	require.Equal(t, "/usr/share/foo/bar", d.DataDir)
 }
 
 func TestUnixAppDirsLocal(t *testing.T) {
@@ -29,6 +35,7 @@ func TestUnixAppDirsLocal(t *testing.T) {
 		Subdir:      "foo/bar",
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -38,6 +45,7 @@ func TestUnixAppDirsLocal(t *testing.T) {
 	require.Equal(t, home+"/.config/foo/bar", d.ConfigDir)
 	require.Equal(t, home+"/.local/state/foo/bar", d.StateDir)
 	require.Equal(t, home+"/.cache/foo/bar", d.CacheDir)
+	require.Equal(t, home+"/.local/share/foo/bar", d.DataDir)
 }
 
 func TestUnixAppDirsSubdirUnix(t *testing.T) {
@@ -46,6 +54,7 @@ func TestUnixAppDirsSubdirUnix(t *testing.T) {
 		SubdirUnix:  "zoo/zar",
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(true, config)
 	require.NoError(t, err)
@@ -53,6 +62,7 @@ func TestUnixAppDirsSubdirUnix(t *testing.T) {
 	require.Equal(t, "/etc/zoo/zar", d.ConfigDir)
 	require.Equal(t, "/var/lib/zoo/zar", d.StateDir)
 	require.Equal(t, "/var/cache/zoo/zar", d.CacheDir)
+	require.Equal(t, "/usr/share/zoo/zar", d.DataDir)
 
 	d, err = RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -62,6 +72,7 @@ func TestUnixAppDirsSubdirUnix(t *testing.T) {
 	require.Equal(t, home+"/.config/zoo/zar", d.ConfigDir)
 	require.Equal(t, home+"/.local/state/zoo/zar", d.StateDir)
 	require.Equal(t, home+"/.cache/zoo/zar", d.CacheDir)
+	require.Equal(t, home+"/.local/share/zoo/zar", d.DataDir)
 }
 
 func TestUnixUserDirs(t *testing.T) {
@@ -92,3 +103,492 @@ func TestUnixUserDirs(t *testing.T) {
 		d.Fonts,
 	)
 }
+
+func TestUnixUserDirsFile(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+
+	err = os.WriteFile(configHome+"/user-dirs.dirs", []byte(`XDG_DESKTOP_DIR="$HOME/"
+XDG_DOWNLOAD_DIR="$HOME/dl"
+XDG_MUSIC_DIR="/srv/music"
+`), 0o644)
+	require.NoError(t, err)
+
+	d, err := RetrieveUserDirs()
+	require.NoError(t, err)
+	require.Equal(t, "", d.Desktop)
+	require.Equal(t, home+"/dl", d.Downloads)
+	require.Equal(t, "/srv/music", d.Music)
+	require.Equal(t, "", d.Documents)
+
+	err = os.WriteFile(configHome+"/user-dirs.dirs", []byte(`XDG_DESKTOP_DIR="$(id)"`), 0o644)
+	require.NoError(t, err)
+	_, err = RetrieveUserDirs()
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+}
+
+func TestUnixAppDirsUsrLocal(t *testing.T) {
+	config := &AppConfig{
+		Subdir:   "foo/bar",
+		UsrLocal: true,
+	}
+	d, err := RetrieveAppDirs(true, config)
+	require.NoError(t, err)
+	require.Equal(t, "/usr/local/share/foo/bar", d.DataDir)
+}
+
+func TestUnixAppDirsRuntime(t *testing.T) {
+	config := &AppConfig{Subdir: "foo/bar"}
+
+	d, err := RetrieveAppDirs(true, config)
+	require.NoError(t, err)
+	require.Equal(t, "/run/foo/bar", d.RuntimeDir)
+
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	d, err = RetrieveAppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, runtimeDir+"/foo/bar", d.RuntimeDir)
+
+	// Fallback
+	tempDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("TMPDIR", tempDir)
+	d, err = RetrieveAppDirs(false, config)
+	require.NoError(t, err)
+	fallback := fmt.Sprintf("%s/runtime-%d", tempDir, os.Getuid())
+	require.Equal(t, fallback+"/foo/bar", d.RuntimeDir)
+	// Not created by RetrieveAppDirs
+	_, err = os.Stat(fallback)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.NoError(t, EnsureRuntimeDir(config))
+	fi, err := os.Stat(fallback)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o700), fi.Mode().Perm())
+
+	// Mode is fixed
+	require.NoError(t, os.Chmod(fallback, 0o777))
+	d, err = RetrieveAppDirs(false, config)
+	require.NoError(t, err)
+	require.Empty(t, d.RuntimeDir)
+	require.NotEmpty(t, d.ConfigDir)
+	require.NoError(t, EnsureRuntimeDir(config))
+	fi, err = os.Stat(fallback)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o700), fi.Mode().Perm())
+
+	// Symlinks are not followed
+	require.NoError(t, os.Remove(fallback))
+	require.NoError(t, os.Symlink(t.TempDir(), fallback))
+	require.ErrorIs(t, EnsureRuntimeDir(config), ErrRuntimeDirUnsafe)
+	d, err = RetrieveAppDirs(false, config)
+	require.NoError(t, err)
+	require.Empty(t, d.RuntimeDir)
+	require.NoError(t, os.Remove(fallback))
+
+	// Relative $TMPDIR is rejected
+	t.Setenv("TMPDIR", "tmp")
+	_, err = RetrieveAppDirs(false, &AppConfig{Subdir: "foo/bar", StrictEnv: true})
+	var invalid *InvalidEnvError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, "TMPDIR", invalid.Name)
+	t.Setenv("TMPDIR", tempDir)
+
+	if os.Getuid() == 0 {
+		runtimeDir := t.TempDir()
+		require.NoError(t, os.Chown(runtimeDir, 65534, 65534))
+		t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+		require.ErrorIs(t, EnsureRuntimeDir(config), ErrRuntimeDirUnsafe)
+
+		// Other directories are still retrieved.
+		d, err = RetrieveAppDirs(false, config)
+		require.NoError(t, err)
+		require.Equal(t, runtimeDir+"/foo/bar", d.RuntimeDir)
+		require.NotEmpty(t, d.ConfigDir)
+	}
+}
+
+func TestUnixSearchDirs(t *testing.T) {
+	config := &AppConfig{Subdir: "foo/bar"}
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+
+	t.Setenv("XDG_CONFIG_DIRS", "")
+	t.Setenv("XDG_DATA_DIRS", "")
+	dirs, err := RetrieveConfigSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/.config/foo/bar", "/etc/xdg/foo/bar", "/etc/foo/bar"}, dirs)
+	dirs, err = RetrieveDataSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/.local/share/foo/bar", "/usr/local/share/foo/bar", "/usr/share/foo/bar"}, dirs)
+
+	t.Setenv("XDG_CONFIG_DIRS", "/opt/xdg::relative:/etc/xdg:/opt/xdg/")
+	t.Setenv("XDG_DATA_DIRS", "/opt/share:/usr/share")
+	dirs, err = RetrieveConfigSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/.config/foo/bar", "/opt/xdg/foo/bar", "/etc/xdg/foo/bar", "/etc/foo/bar"}, dirs)
+	dirs, err = RetrieveDataSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/.local/share/foo/bar", "/opt/share/foo/bar", "/usr/share/foo/bar"}, dirs)
+}
+
+func TestUnixFindConfigFile(t *testing.T) {
+	configHome := t.TempDir()
+	systemDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_CONFIG_DIRS", systemDir)
+	config := &AppConfig{Subdir: "foo"}
+
+	require.NoError(t, os.MkdirAll(configHome+"/foo", 0o755))
+	require.NoError(t, os.MkdirAll(systemDir+"/foo", 0o755))
+	require.NoError(t, os.WriteFile(configHome+"/foo/config.toml", nil, 0o644))
+	require.NoError(t, os.WriteFile(systemDir+"/foo/config.json", nil, 0o644))
+	require.NoError(t, os.WriteFile(systemDir+"/foo/config.toml", nil, 0o644))
+
+	file, err := FindConfigFile(config, "config", ".json", ".toml")
+	require.NoError(t, err)
+	require.Equal(t, configHome+"/foo/config.toml", file)
+
+	files, err := FindConfigFiles(config, "config", ".json", ".toml")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		configHome + "/foo/config.toml",
+		systemDir + "/foo/config.json",
+		systemDir + "/foo/config.toml",
+	}, files)
+
+	file, err = FindConfigFile(config, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, systemDir+"/foo/config.json", file)
+
+	_, err = FindConfigFile(config, "config.yaml")
+	require.ErrorIs(t, err, ErrFileNotFound)
+	files, err = FindDataFiles(config, "config.yaml")
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	_, err = FindConfigFile(config, "../../etc/passwd")
+	require.ErrorIs(t, err, ErrInvalidFileName)
+}
+
+func TestUnixBinDir(t *testing.T) {
+	dir, err := RetrieveBinDir(true)
+	require.NoError(t, err)
+	require.Equal(t, "/usr/local/bin", dir)
+
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+	t.Setenv("XDG_BIN_HOME", "")
+	dir, err = RetrieveBinDir(false)
+	require.NoError(t, err)
+	require.Equal(t, home+"/.local/bin", dir)
+
+	binHome := t.TempDir()
+	t.Setenv("XDG_BIN_HOME", binHome)
+	dir, err = RetrieveBinDir(false)
+	require.NoError(t, err)
+	require.Equal(t, binHome, dir)
+
+	t.Setenv("PATH", "/usr/bin:/bin")
+	require.False(t, IsInPath(dir))
+	t.Setenv("PATH", "/usr/bin:"+binHome+"/:/bin")
+	require.True(t, IsInPath(dir))
+
+	link := t.TempDir() + "/bin"
+	require.NoError(t, os.Symlink(binHome, link))
+	t.Setenv("PATH", "/usr/bin:"+link)
+	require.True(t, IsInPath(dir))
+
+	// Same policy as the other XDG environment variables
+	t.Setenv("XDG_BIN_HOME", "~/bin")
+	dir, err = RetrieveBinDir(false)
+	require.NoError(t, err)
+	require.Equal(t, home+"/.local/bin", dir)
+	dir, err = RetrieveBinDirWithConfig(false, &AppConfig{ExpandTilde: true})
+	require.NoError(t, err)
+	require.Equal(t, home+"/bin", dir)
+	_, err = RetrieveBinDirWithConfig(false, &AppConfig{StrictEnv: true})
+	var invalid *InvalidEnvError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, "XDG_BIN_HOME", invalid.Name)
+}
+
+func TestUnixUserDirsDefaults(t *testing.T) {
+	configHome := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_CONFIG_DIRS", configDir)
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+
+	// Built-in defaults
+	d, err := RetrieveUserDirs()
+	require.NoError(t, err)
+	require.Equal(t, home+"/Desktop", d.Desktop)
+	require.Equal(t, home+"/Downloads", d.Downloads)
+	require.Equal(t, home+"/Videos", d.Videos)
+	require.Equal(t, home+"/Public", d.PublicShare)
+
+	// user-dirs.defaults
+	err = os.WriteFile(configDir+"/user-dirs.defaults", []byte("DOWNLOAD=dl\nDESKTOP=\n"), 0o644)
+	require.NoError(t, err)
+	d, err = RetrieveUserDirs()
+	require.NoError(t, err)
+	require.Equal(t, home+"/dl", d.Downloads)
+	require.Equal(t, "", d.Desktop)
+	require.Equal(t, "", d.Videos)
+
+	// Disabled in user-dirs.conf
+	err = os.WriteFile(configDir+"/user-dirs.conf", []byte("enabled=False\n"), 0o644)
+	require.NoError(t, err)
+	d, err = RetrieveUserDirs()
+	require.NoError(t, err)
+	require.Equal(t, "", d.Downloads)
+
+	// user-dirs.conf in $XDG_CONFIG_HOME takes precedence
+	err = os.WriteFile(configHome+"/user-dirs.conf", []byte("enabled=True\n"), 0o644)
+	require.NoError(t, err)
+	d, err = RetrieveUserDirs()
+	require.NoError(t, err)
+	require.Equal(t, home+"/dl", d.Downloads)
+}
+
+func TestUnixAppDirsInvalidEnv(t *testing.T) {
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+	t.Setenv("XDG_CONFIG_HOME", "relative/config")
+	t.Setenv("XDG_CACHE_HOME", "~/cache")
+
+	config := &AppConfig{Subdir: "foo"}
+	d, err := RetrieveAppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, home+"/.config/foo", d.ConfigDir)
+	require.Equal(t, home+"/.cache/foo", d.CacheDir)
+
+	errs, err := ValidateXDGEnv(config)
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	require.Equal(t, "XDG_CONFIG_HOME", errs[0].Name)
+	require.Equal(t, "XDG_CACHE_HOME", errs[1].Name)
+
+	config.ExpandTilde = true
+	d, err = RetrieveAppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, home+"/cache/foo", d.CacheDir)
+
+	config.StrictEnv = true
+	_, err = RetrieveAppDirs(false, config)
+	var invalid *InvalidEnvError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, "XDG_CONFIG_HOME", invalid.Name)
+}
+
+func TestUnixSetUserDir(t *testing.T) {
+	configHome := t.TempDir() + "/config"
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_CONFIG_DIRS", t.TempDir())
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+
+	// user-dirs.dirs is created with defaults
+	err = SetUserDir(UserDirDownloads, "/mnt/downloads")
+	require.NoError(t, err)
+	d, err := RetrieveUserDirs()
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/downloads", d.Downloads)
+	require.Equal(t, home+"/Desktop", d.Desktop)
+
+	data, err := os.ReadFile(configHome + "/user-dirs.dirs")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "# This file is written by xdg-user-dirs-update\n")
+	require.Contains(t, string(data), "XDG_DESKTOP_DIR=\"$HOME/Desktop\"\n")
+
+	// Round trip
+	d.Documents = home + "/docs"
+	d.Templates = ""
+	err = SaveUserDirs(d)
+	require.NoError(t, err)
+	d2, err := RetrieveUserDirs()
+	require.NoError(t, err)
+	require.Equal(t, d, d2)
+
+	err = SetUserDir(UserDirMusic, "relative")
+	require.Error(t, err)
+
+	// The map of the caller is not modified.
+	dirs := map[string]string{UserDirMusic: ""}
+	require.NoError(t, defaultResolver.newLookup(nil, nil).setUserDirs(dirs))
+	require.Equal(t, "", dirs[UserDirMusic])
+
+	r, err := NewResolverForOS("linux", StaticEnv{Home: "/home/foo"})
+	require.NoError(t, err)
+	require.ErrorIs(t, r.SetUserDir(UserDirMusic, "/mnt/music"), ErrEnvReadOnly)
+}
+
+func TestUnixAppDirsEnvOverride(t *testing.T) {
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+	t.Setenv("MYAPP_CONFIG_DIR", "/srv/myapp/config/")
+	t.Setenv("MYAPP_CACHE", "/tmp/myapp-cache")
+
+	config := &AppConfig{
+		Subdir:    "myapp",
+		EnvPrefix: "MYAPP",
+		EnvVars:   map[DirKind]string{KindCache: "MYAPP_CACHE"},
+	}
+	d, err := RetrieveAppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, "/srv/myapp/config", d.ConfigDir)
+	require.Equal(t, "/tmp/myapp-cache", d.CacheDir)
+	require.Equal(t, home+"/.local/state/myapp", d.StateDir)
+	require.Equal(t, d.CacheDir, d.Dir(KindCache))
+
+	config.RequireEnv = true
+	_, err = RetrieveAppDirs(false, config)
+	var missing *MissingEnvError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, []DirKind{KindState, KindData, KindRuntime}, missing.Kinds)
+	require.Equal(t, []string{"MYAPP_STATE_DIR", "MYAPP_DATA_DIR", "MYAPP_RUNTIME_DIR"}, missing.Vars)
+	require.Contains(t, err.Error(), "state ($MYAPP_STATE_DIR)")
+
+	t.Setenv("MYAPP_STATE_DIR", "relative")
+	_, err = RetrieveAppDirs(false, config)
+	var invalid *InvalidEnvError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, "MYAPP_STATE_DIR", invalid.Name)
+}
+
+func TestUnixTermux(t *testing.T) {
+	home := "/data/data/com.termux/files/home"
+	r := NewResolver(&fakeEnv{
+		home:        home,
+		executables: map[string]string{"termux-setup-storage": "/data/data/com.termux/files/usr/bin/termux-setup-storage"},
+		symlinks: map[string]string{
+			home + "/storage/downloads": "/storage/emulated/0/Download",
+			home + "/storage/shared":    "/storage/emulated/0",
+			home + "/storage/pictures":  "/storage/emulated/0/Pictures",
+			home + "/storage/movies":    "/storage/emulated/0/Movies",
+			home + "/storage/music":     "/storage/emulated/0/Music",
+		},
+	})
+	config := &AppConfig{Subdir: "foo"}
+
+	d, err := r.AppDirs(true, config)
+	require.NoError(t, err)
+	require.Equal(t, "/data/data/com.termux/files/usr/etc/foo", d.ConfigDir)
+	require.Equal(t, "/data/data/com.termux/files/usr/var/lib/foo", d.StateDir)
+	require.Equal(t, "/data/data/com.termux/files/usr/var/cache/foo", d.CacheDir)
+	require.Equal(t, "/data/data/com.termux/files/usr/share/foo", d.DataDir)
+	require.Equal(t, "/data/data/com.termux/files/usr/var/run/foo", d.RuntimeDir)
+
+	d, err = r.AppDirs(false, config)
+	require.NoError(t, err)
+	require.Equal(t, home+"/.config/foo", d.ConfigDir)
+
+	u, err := r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, "", u.Desktop)
+	require.Equal(t, "/storage/emulated/0/Download", u.Downloads)
+	require.Equal(t, "/storage/emulated/0/Documents", u.Documents)
+	require.Equal(t, "/storage/emulated/0/Pictures", u.Pictures)
+	require.Equal(t, "/storage/emulated/0/Movies", u.Videos)
+	require.Equal(t, "/storage/emulated/0/Music", u.Music)
+	require.Nil(t, u.Fonts)
+
+	dirs, err := r.ConfigSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		home + "/.config/foo",
+		"/data/data/com.termux/files/usr/etc/xdg/foo",
+		"/data/data/com.termux/files/usr/etc/foo",
+	}, dirs)
+}
+
+func TestUnixResolverUserDirsFile(t *testing.T) {
+	r := NewResolver(&fakeEnv{
+		home: "/home/foo",
+		vars: map[string]string{"XDG_CONFIG_HOME": "/cfg", "XDG_RUNTIME_DIR": "/run/user/1000"},
+		files: fstest.MapFS{
+			"cfg/user-dirs.dirs": {Data: []byte("XDG_DOWNLOAD_DIR=\"$HOME/dl\"\n")},
+			"cfg/foo/app.toml":   {Data: []byte("")},
+		},
+	})
+
+	u, err := r.UserDirs(nil)
+	require.NoError(t, err)
+	require.Equal(t, "/home/foo/dl", u.Downloads)
+	require.Equal(t, "", u.Desktop)
+
+	d, err := r.AppDirs(false, &AppConfig{Subdir: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "/cfg/foo", d.ConfigDir)
+	require.Equal(t, "/run/user/1000/foo", d.RuntimeDir)
+
+	file, err := r.FindConfigFile(&AppConfig{Subdir: "foo"}, "app", ".json", ".toml")
+	require.NoError(t, err)
+	require.Equal(t, "/cfg/foo/app.toml", file)
+}
+
+func TestUnixAudit(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(root+"/parent/config", 0o700))
+	require.NoError(t, os.Chmod(root+"/parent", 0o777))
+	require.NoError(t, os.Chmod(root+"/parent/config", 0o770))
+	require.NoError(t, os.Mkdir(root+"/shared", 0o777))
+	require.NoError(t, os.Chmod(root+"/shared", 0o777))
+	require.NoError(t, os.Symlink(root+"/shared", root+"/state"))
+
+	d := &AppDirs{
+		ConfigDir: root + "/parent/config",
+		StateDir:  root + "/state",
+		CacheDir:  root + "/nonexistent/cache",
+	}
+	config := &AuditConfig{TrustedRoots: []string{root}}
+	issues, err := d.Audit(config)
+	require.NoError(t, err)
+	require.Equal(t, []AuditIssue{
+		{Kind: KindConfig, Path: root + "/parent/config", Problem: ProblemWritable, Mode: fs.ModeDir | 0o770},
+		{Kind: KindConfig, Path: root + "/parent", Problem: ProblemWritable, Mode: fs.ModeDir | 0o777},
+		{Kind: KindState, Path: root + "/state", Problem: ProblemUnsafeSymlink, Mode: fs.ModeDir | 0o777},
+	}, issues)
+
+	// Parents with the sticky bit are fine.
+	require.NoError(t, os.Chmod(root+"/parent", 0o777|fs.ModeSticky))
+	config.Fix = true
+	issues, err = d.Audit(config)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.True(t, issues[0].Fixed)
+	fi, err := os.Stat(root + "/parent/config")
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o750), fi.Mode().Perm())
+
+	// Setgid bit is kept.
+	require.NoError(t, os.Chmod(root+"/parent/config", 0o770|fs.ModeSetgid))
+	_, err = d.Audit(config)
+	require.NoError(t, err)
+	fi, err = os.Stat(root + "/parent/config")
+	require.NoError(t, err)
+	require.Equal(t, fs.ModeDir|fs.ModeSetgid|0o750, fi.Mode())
+
+	// Parents of the directory a symbolic link points to are checked too.
+	require.NoError(t, os.MkdirAll(root+"/open/data", 0o700))
+	require.NoError(t, os.Chmod(root+"/open", 0o777))
+	require.NoError(t, os.Symlink(root+"/open/data", root+"/data"))
+	issues, err = (&AppDirs{DataDir: root + "/data"}).Audit(config)
+	require.NoError(t, err)
+	require.Equal(t, []AuditIssue{
+		{Kind: KindData, Path: root + "/open", Problem: ProblemWritable, Mode: fs.ModeDir | 0o777},
+	}, issues)
+
+	if os.Getuid() == 0 {
+		require.NoError(t, os.Chown(root+"/parent/config", 65534, 65534))
+		issues, err = d.Audit(config)
+		require.NoError(t, err)
+		require.Equal(t, ProblemForeignOwner, issues[0].Problem)
+	}
+}
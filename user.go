@@ -17,54 +17,111 @@ type UserDirs struct {
 	PublicShare string
 }
 
+// Names of user directories, as used in user-dirs.dirs (e.g. `XDG_DOWNLOAD_DIR`).
+const (
+	UserDirDesktop     = "DESKTOP"
+	UserDirDownloads   = "DOWNLOAD"
+	UserDirDocuments   = "DOCUMENTS"
+	UserDirPictures    = "PICTURES"
+	UserDirVideos      = "VIDEOS"
+	UserDirMusic       = "MUSIC"
+	UserDirTemplates   = "TEMPLATES"
+	UserDirPublicShare = "PUBLICSHARE"
+)
+
+type UserConfig struct {
+	// On Unix, user directories are read from user-dirs.dirs. If true, and
+	// user-dirs.dirs cannot be read or parsed, `xdg-user-dir` command is
+	// run (if it is installed) to retrieve them instead.
+	//
+	// This doesn't have an effect on other systems.
+	XDGUserDirFallback bool
+
+	// Inside strictly confined snaps, $HOME points to a directory of the
+	// snap. If true, and running inside a snap, user directories are
+	// retrieved for the real home directory ($SNAP_REAL_HOME) instead:
+	// user-dirs.dirs is read from it, and defaults are relative to it.
+	//
+	// This doesn't have an effect on other systems.
+	SnapRealHome bool
+}
+
 // On Linux, XDG directories may be unset. If a directory is unset,
 // its value within `UserDirs` struct will be empty.
 func RetrieveUserDirs() (userDirs *UserDirs, err error) {
+	return RetrieveUserDirsWithConfig(nil)
+}
+
+// Same as `RetrieveUserDirs`, but with a config. `config` can be nil.
+func RetrieveUserDirsWithConfig(config *UserConfig) (userDirs *UserDirs, err error) {
+	return defaultResolver.UserDirs(config)
+}
+
+// UserDirs is the same as `RetrieveUserDirsWithConfig`, but it uses the
+// environment of the resolver. `config` can be nil.
+func (r *Resolver) UserDirs(config *UserConfig) (userDirs *UserDirs, err error) {
+	if r.memo != nil {
+		if config == nil {
+			config = new(UserConfig)
+		}
+		v, err := r.memoized(memoKey("UserDirs", *config), func(r *Resolver) (any, error) {
+			return r.UserDirs(config)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Copied, so that the memoized value cannot be modified.
+		userDirs := *v.(*UserDirs)
+		userDirs.Fonts = append([]string(nil), userDirs.Fonts...)
+		return &userDirs, nil
+	}
+
+	l := r.newLookup(nil, config)
 	userDirs = new(UserDirs)
 
-	userDirs.Desktop, err = desktopDir()
+	userDirs.Desktop, err = l.desktopDir()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
 	}
 	userDirs.Desktop = filepath.ToSlash(userDirs.Desktop)
 
-	userDirs.Downloads, err = downloadsDir()
+	userDirs.Downloads, err = l.downloadsDir()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
 	}
 	userDirs.Downloads = filepath.ToSlash(userDirs.Downloads)
 
-	userDirs.Documents, err = documentsDir()
+	userDirs.Documents, err = l.documentsDir()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
 	}
 	userDirs.Documents = filepath.ToSlash(userDirs.Documents)
 
-	userDirs.Pictures, err = picturesDir()
+	userDirs.Pictures, err = l.picturesDir()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
 	}
 	userDirs.Pictures = filepath.ToSlash(userDirs.Pictures)
 
-	userDirs.Videos, err = videosDir()
+	userDirs.Videos, err = l.videosDir()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
 	}
 	userDirs.Videos = filepath.ToSlash(userDirs.Videos)
 
-	userDirs.Music, err = musicDir()
+	userDirs.Music, err = l.musicDir()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
 	}
 	userDirs.Music = filepath.ToSlash(userDirs.Music)
 
-	userDirs.Fonts, err = fontsDirs()
+	userDirs.Fonts, err = l.fontsDirs()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
@@ -73,14 +130,14 @@ func RetrieveUserDirs() (userDirs *UserDirs, err error) {
 		userDirs.Fonts[i] = filepath.ToSlash(font)
 	}
 
-	userDirs.Templates, err = templatesDir()
+	userDirs.Templates, err = l.templatesDir()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
 	}
 	userDirs.Templates = filepath.ToSlash(userDirs.Templates)
 
-	userDirs.PublicShare, err = publicShareDir()
+	userDirs.PublicShare, err = l.publicShareDir()
 	if err != nil {
 		err = fmt.Errorf("finddirs: %w", err)
 		return
@@ -89,3 +146,63 @@ func RetrieveUserDirs() (userDirs *UserDirs, err error) {
 
 	return
 }
+
+// SetUserDir sets the user directory `name` (e.g. `UserDirDownloads`) to `dir`,
+// just like `xdg-user-dirs-update --set` does. `dir` must be an absolute path.
+// If it is empty, the directory is unset.
+//
+// On Unix, the entry is updated inside user-dirs.dirs atomically. Comments and
+// other entries are kept. If `dir` is inside the home directory, it is written
+// relative to $HOME. If user-dirs.dirs doesn't exist, it is created with the
+// default directories (see `RetrieveUserDirs`).
+//
+// Only supported on Unix (except Termux). On other systems,
+// `ErrOSNotSupportedSetUserDirs` is returned.
+func SetUserDir(name string, dir string) error {
+	return defaultResolver.SetUserDir(name, dir)
+}
+
+// SaveUserDirs writes all directories in `userDirs` (except `Fonts`) to
+// user-dirs.dirs. Empty directories are unset. Values returned from
+// `RetrieveUserDirs` can be modified and saved back with it.
+//
+// See `SetUserDir` for details.
+func SaveUserDirs(userDirs *UserDirs) error {
+	return defaultResolver.SaveUserDirs(userDirs)
+}
+
+// SetUserDir is the same as the package-level `SetUserDir`, but it updates
+// user-dirs.dirs of the environment of the resolver. With a `UserEnv`, the
+// file and the directories that are created are owned by its user, and if
+// a symbolic link leads outside the directories the user owns,
+// `ErrUserPathUnsafe` is returned. Files can only be written with `OSEnv`
+// and `UserEnv`; with other environments, `ErrEnvReadOnly` is returned.
+func (r *Resolver) SetUserDir(name string, dir string) error {
+	return r.setUserDirs(map[string]string{name: filepath.ToSlash(dir)})
+}
+
+// SaveUserDirs is the same as the package-level `SaveUserDirs`, but it
+// updates user-dirs.dirs of the environment of the resolver. See
+// `(*Resolver).SetUserDir` for details.
+func (r *Resolver) SaveUserDirs(userDirs *UserDirs) error {
+	return r.setUserDirs(map[string]string{
+		UserDirDesktop:     filepath.ToSlash(userDirs.Desktop),
+		UserDirDownloads:   filepath.ToSlash(userDirs.Downloads),
+		UserDirDocuments:   filepath.ToSlash(userDirs.Documents),
+		UserDirPictures:    filepath.ToSlash(userDirs.Pictures),
+		UserDirVideos:      filepath.ToSlash(userDirs.Videos),
+		UserDirMusic:       filepath.ToSlash(userDirs.Music),
+		UserDirTemplates:   filepath.ToSlash(userDirs.Templates),
+		UserDirPublicShare: filepath.ToSlash(userDirs.PublicShare),
+	})
+}
+
+func (r *Resolver) setUserDirs(dirs map[string]string) error {
+	err := r.newLookup(nil, nil).setUserDirs(dirs)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	// Memoized user directories are out of date.
+	r.Invalidate()
+	return nil
+}
@@ -0,0 +1,422 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// UserEnv is the environment of a user account other than the one the
+// current process runs as, such as the user who invoked it through sudo.
+// The home directory is read from the user database (e.g. /etc/passwd), and
+// files (e.g. user-dirs.dirs) are read from disk.
+//
+// Environment variables of another user cannot be read. XDG environment
+// variables that cannot be recovered are listed in `Unknown`; defaults are
+// used for them, which are correct unless the user has changed them (e.g.
+// in their shell profile).
+type UserEnv struct {
+	// Name, user ID, and group ID of the user. Use them to change the owner
+	// of created files back to the user (e.g. with os.Chown).
+	Username string
+	UID      int
+	GID      int
+	// Home directory of the user.
+	Home string
+
+	// XDG environment variables that couldn't be recovered.
+	Unknown []string
+
+	// Recovered XDG environment variables. Unknown ones are empty.
+	vars map[string]string
+	// If true, other environment variables are read from the current process.
+	inheritEnv bool
+}
+
+// XDG environment variables `UserEnv` tries to recover.
+var userEnvXDGVars = []string{
+	"XDG_CONFIG_HOME",
+	"XDG_STATE_HOME",
+	"XDG_CACHE_HOME",
+	"XDG_DATA_HOME",
+	"XDG_BIN_HOME",
+	"XDG_RUNTIME_DIR",
+	"XDG_CONFIG_DIRS",
+	"XDG_DATA_DIRS",
+}
+
+// InvokingUserEnv returns the environment of the user who invoked the
+// current process through sudo ($SUDO_UID or $SUDO_USER) or pkexec
+// ($PKEXEC_UID). If the process is not run through them, or it runs as the
+// invoking user, nil is returned. Use it with `NewResolver` to retrieve the
+// directories of the invoking user instead of the ones of root:
+//
+//	env, err := InvokingUserEnv()
+//	if env != nil {
+//		r := NewResolver(env)
+//		appDirs, err := r.AppDirs(false, config)
+//	}
+//
+// Since the process runs in the session of the invoking user, environment
+// variables are read from the current process. XDG environment variables
+// are used only if they are preserved (e.g. with `sudo -E`), and their
+// values are inside the home directory of the user. $XDG_RUNTIME_DIR is
+// recovered from /run/user/<uid> if it exists.
+//
+// Not supported on Windows and Plan 9, where nil is always returned.
+func InvokingUserEnv() (*UserEnv, error) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		return nil, nil
+	}
+
+	var (
+		u   *user.User
+		err error
+	)
+	if uid := os.Getenv("SUDO_UID"); uid != "" {
+		u, err = user.LookupId(uid)
+	} else if name := os.Getenv("SUDO_USER"); name != "" {
+		u, err = user.Lookup(name)
+	} else if uid := os.Getenv("PKEXEC_UID"); uid != "" {
+		u, err = user.LookupId(uid)
+	} else {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	if u.Uid == strconv.Itoa(os.Getuid()) {
+		return nil, nil
+	}
+
+	e, err := newUserEnv(u)
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	e.inheritEnv = true
+	for _, name := range userEnvXDGVars {
+		value := os.Getenv(name)
+		if value == "" || !e.validXDGValue(name, value) {
+			continue
+		}
+		e.vars[name] = value
+	}
+	e.recoverRuntimeDir()
+	e.setUnknown()
+	return e, nil
+}
+
+// LookupUserEnv returns the environment of the user `username`, which can
+// also be a numeric user ID. It doesn't depend on the environment of the
+// current process: only $XDG_RUNTIME_DIR is recovered (from /run/user/<uid>
+// if it exists); other XDG environment variables are listed in `Unknown`.
+// Executables (e.g. xdg-user-dir) are searched in a fixed $PATH
+// (/usr/local/bin:/usr/bin:/bin).
+//
+// Not supported on Windows and Plan 9, where `ErrOSNotSupportedUserEnv`
+// is returned.
+func LookupUserEnv(username string) (*UserEnv, error) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		return nil, fmt.Errorf("finddirs: %w", ErrOSNotSupportedUserEnv)
+	}
+	u, err := user.Lookup(username)
+	var unknownUserErr user.UnknownUserError
+	if errors.As(err, &unknownUserErr) {
+		if _, atoiErr := strconv.Atoi(username); atoiErr == nil {
+			u, err = user.LookupId(username)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+
+	e, err := newUserEnv(u)
+	if err != nil {
+		return nil, fmt.Errorf("finddirs: %w", err)
+	}
+	e.recoverRuntimeDir()
+	e.setUnknown()
+	return e, nil
+}
+
+// RetrieveAppDirsForUser retrieves the local application directories of the
+// user `username` (or numeric user ID), e.g. for administrative tools that
+// run as root. See `LookupUserEnv` for how the environment of the user is
+// determined. `config` can be nil.
+//
+// `unknown` lists the XDG environment variables that couldn't be
+// determined. Defaults are used for them, so the directories are only
+// correct if the user hasn't set them (e.g. in their shell profile).
+func RetrieveAppDirsForUser(username string, config *AppConfig) (appDirs *AppDirs, unknown []string, err error) {
+	env, err := LookupUserEnv(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	appDirs, err = NewResolver(env).AppDirs(false, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return appDirs, env.Unknown, nil
+}
+
+// RetrieveUserDirsForUser is the `RetrieveUserDirsWithConfig` counterpart
+// of `RetrieveAppDirsForUser`. user-dirs.dirs of the user is read from
+// disk. `config` can be nil.
+func RetrieveUserDirsForUser(username string, config *UserConfig) (userDirs *UserDirs, unknown []string, err error) {
+	env, err := LookupUserEnv(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	userDirs, err = NewResolver(env).UserDirs(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return userDirs, env.Unknown, nil
+}
+
+func newUserEnv(u *user.User) (*UserEnv, error) {
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID of %s: %q", u.Username, u.Uid)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group ID of %s: %q", u.Username, u.Gid)
+	}
+	if !filepath.IsAbs(u.HomeDir) {
+		return nil, fmt.Errorf("home directory of %s is not an absolute path: %q", u.Username, u.HomeDir)
+	}
+	return &UserEnv{
+		Username: u.Username,
+		UID:      uid,
+		GID:      gid,
+		Home:     u.HomeDir,
+		vars:     make(map[string]string),
+	}, nil
+}
+
+// validXDGValue reports whether the value of the XDG environment variable
+// `name` belongs to the user. Variables that point to user-level
+// directories must be inside the home directory of the user, otherwise
+// they are the ones of the current user.
+func (e *UserEnv) validXDGValue(name, value string) bool {
+	switch name {
+	case "XDG_CONFIG_DIRS", "XDG_DATA_DIRS":
+		return true
+	case "XDG_RUNTIME_DIR":
+		return value == e.runtimeDir()
+	}
+	home := filepath.ToSlash(filepath.Clean(e.Home))
+	value = filepath.ToSlash(filepath.Clean(value))
+	return path.IsAbs(value) && (value == home || strings.HasPrefix(value, home+"/"))
+}
+
+func (e *UserEnv) runtimeDir() string { return fmt.Sprintf("/run/user/%d", e.UID) }
+
+// recoverRuntimeDir sets $XDG_RUNTIME_DIR to /run/user/<uid>, where
+// systemd-logind creates it, if it exists.
+func (e *UserEnv) recoverRuntimeDir() {
+	if e.vars["XDG_RUNTIME_DIR"] != "" {
+		return
+	}
+	fi, err := os.Stat(e.runtimeDir())
+	if err == nil && fi.IsDir() {
+		e.vars["XDG_RUNTIME_DIR"] = e.runtimeDir()
+	}
+}
+
+func (e *UserEnv) setUnknown() {
+	e.Unknown = nil
+	for _, name := range userEnvXDGVars {
+		if e.vars[name] == "" {
+			e.Unknown = append(e.Unknown, name)
+		}
+	}
+}
+
+// Getenv returns the recovered value of XDG environment variables, and the
+// home directory and the name of the user for $HOME, $USER and $LOGNAME.
+// For environments returned by `LookupUserEnv`, $PATH is a fixed default.
+func (e *UserEnv) Getenv(key string) string {
+	if contains(userEnvXDGVars, key) {
+		return e.vars[key]
+	}
+	switch key {
+	case "HOME":
+		return e.Home
+	case "USER", "LOGNAME":
+		return e.Username
+	case "PATH":
+		if !e.inheritEnv {
+			return userEnvPath
+		}
+	}
+	if e.inheritEnv {
+		return os.Getenv(key)
+	}
+	return ""
+}
+
+// $PATH of environments returned by `LookupUserEnv`, since the one of the
+// user is unknown. Same as the default of login(1).
+const userEnvPath = "/usr/local/bin:/usr/bin:/bin"
+
+// LookPath searches $PATH of the environment (see `Getenv`), instead of the
+// one of the current process.
+func (e *UserEnv) LookPath(file string) (string, error) {
+	if strings.Contains(file, "/") {
+		err := findExecutable(file)
+		if err != nil {
+			return "", &exec.Error{Name: file, Err: err}
+		}
+		return file, nil
+	}
+	for _, dir := range filepath.SplitList(e.Getenv("PATH")) {
+		// Relative entries (including empty ones, which mean the current
+		// directory) depend on the current process.
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		name := filepath.Join(dir, file)
+		if findExecutable(name) == nil {
+			return name, nil
+		}
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// findExecutable checks whether `file` is an executable, just like
+// exec.LookPath does.
+func findExecutable(file string) error {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() || fi.Mode().Perm()&0o111 == 0 {
+		return fs.ErrPermission
+	}
+	return nil
+}
+
+func (e *UserEnv) HomeDir() (string, error)              { return e.Home, nil }
+func (e *UserEnv) ReadFile(name string) ([]byte, error)  { return os.ReadFile(name) }
+func (e *UserEnv) Readlink(name string) (string, error)  { return os.Readlink(name) }
+func (e *UserEnv) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+func (e *UserEnv) UserID() int                           { return e.UID }
+
+// UserEnv doesn't implement `CommandEnv`: xdg-user-dir sources
+// user-dirs.dirs with a shell, which would run what the user has written
+// into it with the privileges of the current process.
+
+// The file and the directories that are created are owned by the user.
+// Since the process usually runs as root, symbolic links the user controls
+// are only followed to directories the user owns (see `userPath`).
+func (e *UserEnv) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	name = filepath.FromSlash(name)
+	dir, missing, err := e.userPath(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	for _, component := range missing {
+		dir = filepath.Join(dir, component)
+		err = os.Mkdir(dir, 0o700)
+		if err != nil {
+			return err
+		}
+		err = os.Lchown(dir, e.UID, e.GID)
+		if err != nil {
+			return err
+		}
+	}
+	name = filepath.Join(dir, filepath.Base(name))
+	err = writeFileAtomic(name, data, perm)
+	if err != nil {
+		return err
+	}
+	return os.Lchown(name, e.UID, e.GID)
+}
+
+// userPath resolves symbolic links in the absolute path `dir` one component
+// at a time, and returns the directory that exists, and the components
+// after it that don't exist.
+//
+// Once a directory that is owned by the user is reached, every directory
+// after it (including the ones symbolic links point to) must be owned by
+// the user too, and directories before it must be owned by root. Otherwise
+// a symbolic link the user has created (e.g. ~/.config pointing to /etc)
+// could make the process write to a place the user cannot, and
+// `ErrUserPathUnsafe` is returned.
+func (e *UserEnv) userPath(dir string) (resolved string, missing []string, err error) {
+	if !filepath.IsAbs(dir) {
+		return "", nil, fmt.Errorf("%w: %s is relative", ErrUserPathUnsafe, filepath.ToSlash(dir))
+	}
+	rest := splitPath(dir)
+	resolved = string(filepath.Separator)
+	owned := false
+	links := 0
+	for len(rest) > 0 {
+		component := rest[0]
+		rest = rest[1:]
+		// ".." is only reached through symbolic links, since `dir` is
+		// cleaned. The parent is checked like any other directory.
+		next := filepath.Join(resolved, component)
+		fi, err := os.Lstat(next)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing = append([]string{component}, rest...)
+			if contains(missing, "..") {
+				return "", nil, fmt.Errorf("%w: %s doesn't exist", ErrUserPathUnsafe, filepath.ToSlash(next))
+			}
+			return resolved, missing, nil
+		} else if err != nil {
+			return "", nil, err
+		}
+
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			// Same limit as Linux.
+			links++
+			if links > 40 {
+				return "", nil, fmt.Errorf("%w: too many symbolic links in %s", ErrUserPathUnsafe, filepath.ToSlash(dir))
+			}
+			target, err := os.Readlink(next)
+			if err != nil {
+				return "", nil, err
+			}
+			if filepath.IsAbs(target) {
+				resolved = string(filepath.Separator)
+			}
+			rest = append(splitPath(target), rest...)
+			continue
+		}
+		if !fi.IsDir() {
+			return "", nil, fmt.Errorf("%w: %s", ErrNotDirectory, filepath.ToSlash(next))
+		}
+		owner, ok := fileOwner(fi)
+		switch {
+		case ok && owner == e.UID:
+			owned = true
+		case !ok || owned || owner != 0:
+			return "", nil, fmt.Errorf("%w: %s is not owned by %s", ErrUserPathUnsafe, filepath.ToSlash(next), e.Username)
+		}
+		resolved = next
+	}
+	return resolved, nil, nil
+}
+
+// splitPath splits `p` into its components, leaving out empty ones and ".".
+func splitPath(p string) []string {
+	var components []string
+	for _, component := range strings.Split(filepath.Clean(p), string(filepath.Separator)) {
+		if component != "" && component != "." {
+			components = append(components, component)
+		}
+	}
+	return components
+}
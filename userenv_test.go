@@ -0,0 +1,164 @@
+//go:build unix
+
+package finddirs
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvokingUserEnv(t *testing.T) {
+	nobody, err := user.Lookup("nobody")
+	if err != nil || nobody.Uid == strconv.Itoa(os.Getuid()) {
+		t.Skip("user nobody is required")
+	}
+	for _, name := range []string{"SUDO_UID", "SUDO_USER", "PKEXEC_UID"} {
+		t.Setenv(name, "")
+	}
+
+	env, err := InvokingUserEnv()
+	require.NoError(t, err)
+	require.Nil(t, env)
+
+	t.Setenv("SUDO_USER", "nobody")
+	t.Setenv("XDG_CONFIG_HOME", nobody.HomeDir+"/cfg")
+	// Belongs to the current user
+	t.Setenv("XDG_CACHE_HOME", "/root/.cache")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/0")
+	t.Setenv("TMPDIR", t.TempDir())
+	env, err = InvokingUserEnv()
+	require.NoError(t, err)
+	require.NotNil(t, env)
+	require.Equal(t, "nobody", env.Username)
+	require.Equal(t, nobody.Uid, strconv.Itoa(env.UID))
+	require.Equal(t, nobody.Gid, strconv.Itoa(env.GID))
+	require.Equal(t, nobody.HomeDir, env.Home)
+	require.Equal(t, nobody.HomeDir, env.Getenv("HOME"))
+	require.Equal(t, "", env.Getenv("XDG_CACHE_HOME"))
+	require.Contains(t, env.Unknown, "XDG_CACHE_HOME")
+	require.NotContains(t, env.Unknown, "XDG_CONFIG_HOME")
+	require.Equal(t, os.Getenv("TMPDIR"), env.Getenv("TMPDIR"))
+
+	r := NewResolver(env)
+	d, err := r.AppDirs(false, &AppConfig{Subdir: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, nobody.HomeDir+"/cfg/foo", d.ConfigDir)
+	require.Equal(t, nobody.HomeDir+"/.cache/foo", d.CacheDir)
+	if os.Getuid() != 0 {
+		// Runtime directory cannot be created for another user.
+		return
+	}
+	if env.Getenv("XDG_RUNTIME_DIR") == "" {
+		require.Equal(t, os.Getenv("TMPDIR")+"/runtime-"+nobody.Uid+"/foo", d.RuntimeDir)
+		require.NoError(t, r.EnsureRuntimeDir(nil))
+		fi, err := os.Stat(os.Getenv("TMPDIR") + "/runtime-" + nobody.Uid)
+		require.NoError(t, err)
+		uid, _ := fileOwner(fi)
+		require.Equal(t, env.UID, uid)
+	}
+}
+
+func TestRetrieveAppDirsForUser(t *testing.T) {
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("user nobody is required")
+	}
+	// Environment of the current process is not used.
+	t.Setenv("XDG_CONFIG_HOME", nobody.HomeDir+"/cfg")
+	t.Setenv("TMPDIR", t.TempDir())
+
+	for _, name := range []string{"nobody", nobody.Uid} {
+		d, unknown, err := RetrieveAppDirsForUser(name, &AppConfig{Subdir: "foo"})
+		require.NoError(t, err)
+		require.Equal(t, nobody.HomeDir+"/.config/foo", d.ConfigDir)
+		require.Equal(t, nobody.HomeDir+"/.local/state/foo", d.StateDir)
+		require.Contains(t, unknown, "XDG_CONFIG_HOME")
+		// Runtime directory is not created.
+		if !strings.HasPrefix(d.RuntimeDir, "/run/user/") {
+			_, err = os.Stat(path.Dir(d.RuntimeDir))
+			require.ErrorIs(t, err, fs.ErrNotExist)
+		}
+	}
+
+	u, unknown, err := RetrieveUserDirsForUser("nobody", nil)
+	require.NoError(t, err)
+	require.Contains(t, unknown, "XDG_CONFIG_HOME")
+	if _, err := os.Stat(nobody.HomeDir + "/.config/user-dirs.dirs"); os.IsNotExist(err) {
+		require.Equal(t, nobody.HomeDir+"/Downloads", u.Downloads)
+	}
+
+	_, _, err = RetrieveAppDirsForUser("finddirs-no-such-user", nil)
+	require.Error(t, err)
+}
+
+func TestUserEnvWriteFile(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("root is required")
+	}
+	env := &UserEnv{Username: "nobody", UID: 65534, GID: 65534}
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	require.NoError(t, os.Mkdir(home, 0o700))
+	require.NoError(t, os.Chown(home, env.UID, env.GID))
+
+	// Created directories are owned by the user.
+	require.NoError(t, env.WriteFile(home+"/.config/foo/file", []byte("foo"), 0o600))
+	for _, name := range []string{"/.config", "/.config/foo", "/.config/foo/file"} {
+		fi, err := os.Lstat(home + name)
+		require.NoError(t, err)
+		uid, _ := fileOwner(fi)
+		require.Equal(t, env.UID, uid, name)
+	}
+
+	// Symbolic links to directories the user owns are followed.
+	dotfiles := filepath.Join(home, "dotfiles")
+	require.NoError(t, os.Mkdir(dotfiles, 0o700))
+	require.NoError(t, os.Chown(dotfiles, env.UID, env.GID))
+	require.NoError(t, os.Symlink("dotfiles", home+"/.dotconfig"))
+	require.NoError(t, env.WriteFile(home+"/.dotconfig/file", []byte("foo"), 0o600))
+	_, err := os.Stat(dotfiles + "/file")
+	require.NoError(t, err)
+
+	// Others are not.
+	etc := filepath.Join(root, "etc")
+	require.NoError(t, os.Mkdir(etc, 0o755))
+	require.NoError(t, os.Symlink(etc, home+"/.etc"))
+	err = env.WriteFile(home+"/.etc/foo/file", []byte("foo"), 0o600)
+	require.ErrorIs(t, err, ErrUserPathUnsafe)
+	_, err = os.Stat(etc + "/foo")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.NoError(t, os.Symlink("..", home+"/.up"))
+	err = env.WriteFile(home+"/.up/file", []byte("foo"), 0o600)
+	require.ErrorIs(t, err, ErrUserPathUnsafe)
+}
+
+func TestLookupUserEnvPath(t *testing.T) {
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("user nobody is required")
+	}
+	// $PATH of the current process is not used.
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "finddirs-test"), []byte("#!/bin/sh\n"), 0o755))
+	t.Setenv("PATH", bin)
+	_, err = exec.LookPath("finddirs-test")
+	require.NoError(t, err)
+
+	env, err := LookupUserEnv(nobody.Username)
+	require.NoError(t, err)
+	require.Equal(t, "/usr/local/bin:/usr/bin:/bin", env.Getenv("PATH"))
+	_, err = env.LookPath("finddirs-test")
+	require.ErrorIs(t, err, exec.ErrNotFound)
+	sh, err := env.LookPath("sh")
+	require.NoError(t, err)
+	require.Contains(t, []string{"/usr/local/bin/sh", "/usr/bin/sh", "/bin/sh"}, sh)
+}
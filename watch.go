@@ -0,0 +1,392 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchDebounce     = 100 * time.Millisecond
+	defaultWatchPollInterval = time.Second
+)
+
+type WatchOp int
+
+const (
+	// File is created, or moved into the directory.
+	WatchCreate WatchOp = iota
+	// Contents, size, or mode of the file have changed, or it is replaced
+	// (e.g. by an editor that writes to a temporary file and renames it).
+	WatchModify
+	// File is removed, or moved out of the directory.
+	WatchRemove
+)
+
+func (op WatchOp) String() string {
+	switch op {
+	case WatchCreate:
+		return "create"
+	case WatchModify:
+		return "modify"
+	case WatchRemove:
+		return "remove"
+	}
+	return fmt.Sprintf("WatchOp(%d)", int(op))
+}
+
+type WatchEvent struct {
+	// Path of the file, with forward slashes.
+	Path string
+	Op   WatchOp
+}
+
+type WatcherConfig struct {
+	// Other directories to watch along with `ConfigDir`, such as the
+	// system-wide directories `RetrieveConfigSearchDirs` returns.
+	SearchDirs []string
+
+	// Changes are reported once no further changes happen for this long,
+	// so that a file that is written in several steps is reported once.
+	// If 0, 100 milliseconds is used.
+	Debounce time.Duration
+
+	// If true, directories are polled even if inotify is available. Useful
+	// for network filesystems, where inotify doesn't report changes made
+	// by other hosts.
+	Poll bool
+
+	// Interval to poll directories at. If 0, 1 second is used.
+	PollInterval time.Duration
+}
+
+// Watcher reports changes to files inside the config directory, and inside
+// its subdirectories.
+//
+// Directories that don't exist are watched too: files inside them are
+// reported as created once they are created. On Linux, inotify is used.
+// On other systems, and if inotify cannot be used, directories are polled.
+type Watcher struct {
+	// Changes to files. Events are sorted by path. It is closed by `Close`.
+	Events <-chan WatchEvent
+	// Errors that happen while watching. Errors are dropped if they are
+	// not received. It is never closed.
+	Errors <-chan error
+
+	roots    []string
+	config   WatcherConfig
+	notifier notifier
+	files    map[string]fileState
+	events   chan WatchEvent
+	errors   chan error
+
+	closeOnce sync.Once
+	closeErr  error
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// notifier reports changes inside directories, so that they don't need to
+// be polled.
+type notifier interface {
+	// watch replaces the set of watched directories with `dirs`.
+	watch(dirs []string) error
+	// changes receives paths of changed files and directories. Empty path
+	// means that changes might have been lost. It is closed if the notifier
+	// stops working.
+	changes() <-chan string
+	close() error
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+func (s fileState) equal(other fileState) bool {
+	return s.size == other.size && s.modTime.Equal(other.modTime) && s.mode == other.mode
+}
+
+// WatchConfigDir starts watching `ConfigDir`, and the directories in
+// `config.SearchDirs`. `config` can be nil. Call `Close` to stop watching.
+func (d *AppDirs) WatchConfigDir(config *WatcherConfig) (*Watcher, error) {
+	if config == nil {
+		config = new(WatcherConfig)
+	}
+	w := &Watcher{
+		config:  *config,
+		events:  make(chan WatchEvent, 16),
+		errors:  make(chan error, 16),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	w.Events, w.Errors = w.events, w.errors
+	if w.config.Debounce <= 0 {
+		w.config.Debounce = defaultWatchDebounce
+	}
+	if w.config.PollInterval <= 0 {
+		w.config.PollInterval = defaultWatchPollInterval
+	}
+
+	for _, dir := range append([]string{d.ConfigDir}, config.SearchDirs...) {
+		if dir != "" {
+			w.roots = appendUnique(w.roots, filepath.FromSlash(dir))
+		}
+	}
+	if len(w.roots) == 0 {
+		return nil, fmt.Errorf("finddirs: config directory is not set")
+	}
+
+	if !w.config.Poll {
+		n, err := newNotifier()
+		if err != nil {
+			w.reportError(err)
+		}
+		w.notifier = n
+	}
+
+	var err error
+	w.files, err = w.scanAndWatch()
+	if err != nil {
+		w.reportError(err)
+		w.notifier.close()
+		w.notifier = nil
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching, and closes `Events`.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		<-w.stopped
+		if w.notifier != nil {
+			err := w.notifier.close()
+			if err != nil {
+				w.closeErr = fmt.Errorf("finddirs: %w", err)
+			}
+		}
+	})
+	return w.closeErr
+}
+
+func (w *Watcher) run() {
+	defer close(w.stopped)
+	defer close(w.events)
+
+	var (
+		changes <-chan string
+		poll    <-chan time.Time
+		ticker  *time.Ticker
+		timer   = time.NewTimer(time.Hour)
+		pending bool
+		touched = make(map[string]bool)
+	)
+	timer.Stop()
+	defer timer.Stop()
+	startPolling := func() {
+		if ticker == nil {
+			ticker = time.NewTicker(w.config.PollInterval)
+			poll = ticker.C
+		}
+	}
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+	if w.notifier != nil {
+		changes = w.notifier.changes()
+	} else {
+		startPolling()
+	}
+	// restart starts the timer, or restarts it if it is already running.
+	restart := func() {
+		if !timer.Stop() && pending {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(w.config.Debounce)
+		pending = true
+	}
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case p, ok := <-changes:
+			if !ok {
+				// Notifier has stopped working.
+				changes = nil
+				startPolling()
+				restart()
+				continue
+			}
+			if p != "" {
+				touched[filepath.ToSlash(p)] = true
+			}
+			restart()
+		case <-poll:
+			if !pending {
+				restart()
+			}
+		case <-timer.C:
+			pending = false
+			var files map[string]fileState
+			if changes != nil {
+				var err error
+				files, err = w.scanAndWatch()
+				if err != nil {
+					w.reportError(err)
+					startPolling()
+				}
+			} else {
+				files, _ = w.scan()
+			}
+			events := diffFiles(w.files, files, touched)
+			w.files = files
+			touched = make(map[string]bool)
+			for _, event := range events {
+				select {
+				case w.events <- event:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// scanAndWatch scans the watched directories, and watches the directories
+// the scan returns. Directories are scanned again after they are watched,
+// since files and directories might be created before the watches are
+// added. This is repeated until no new directory is found.
+func (w *Watcher) scanAndWatch() (files map[string]fileState, err error) {
+	files, dirs := w.scan()
+	if w.notifier == nil {
+		return files, nil
+	}
+	for {
+		err = w.notifier.watch(dirs)
+		if err != nil {
+			return files, err
+		}
+		var rescanned []string
+		files, rescanned = w.scan()
+		if !hasNew(dirs, rescanned) {
+			return files, nil
+		}
+		dirs = rescanned
+	}
+}
+
+// hasNew reports whether `s` contains an element that is not in `old`.
+func hasNew(old, s []string) bool {
+	for _, v := range s {
+		if !contains(old, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// scan returns the files inside the watched directories, and the
+// directories to watch for changes. If a watched directory doesn't
+// exist, its nearest ancestor that exists is watched instead, so that
+// its creation is noticed.
+func (w *Watcher) scan() (files map[string]fileState, dirs []string) {
+	files = make(map[string]fileState)
+	for _, root := range w.roots {
+		fi, err := os.Stat(root)
+		if err != nil || !fi.IsDir() {
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				w.reportError(err)
+			}
+			if ancestor := existingAncestor(root); ancestor != "" {
+				dirs = appendUnique(dirs, ancestor)
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// Removed while walking
+				if !errors.Is(err, fs.ErrNotExist) {
+					w.reportError(err)
+				}
+				return nil
+			}
+			if entry.IsDir() {
+				dirs = appendUnique(dirs, filePath)
+				return nil
+			}
+			fi, err := entry.Info()
+			if err != nil {
+				if !errors.Is(err, fs.ErrNotExist) {
+					w.reportError(err)
+				}
+				return nil
+			}
+			files[filepath.ToSlash(filePath)] = fileState{size: fi.Size(), modTime: fi.ModTime(), mode: fi.Mode()}
+			return nil
+		})
+		if err != nil {
+			w.reportError(err)
+		}
+	}
+	return files, dirs
+}
+
+func (w *Watcher) reportError(err error) {
+	select {
+	case w.errors <- fmt.Errorf("finddirs: %w", err):
+	default:
+	}
+}
+
+// existingAncestor returns the nearest ancestor of `dir` that exists and is a
+// directory. If there is none, empty string is returned.
+func existingAncestor(dir string) string {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+		fi, err := os.Stat(dir)
+		if err == nil && fi.IsDir() {
+			return dir
+		}
+	}
+}
+
+// diffFiles returns the changes between two scans. Files in `touched` that
+// look the same in both scans are reported as modified, since notifiers can
+// see changes that don't change the size or the modification time.
+func diffFiles(old, new map[string]fileState, touched map[string]bool) []WatchEvent {
+	var events []WatchEvent
+	for p, state := range new {
+		oldState, ok := old[p]
+		if !ok {
+			events = append(events, WatchEvent{Path: p, Op: WatchCreate})
+		} else if !state.equal(oldState) || touched[p] {
+			events = append(events, WatchEvent{Path: p, Op: WatchModify})
+		}
+	}
+	for p := range old {
+		if _, ok := new[p]; !ok {
+			events = append(events, WatchEvent{Path: p, Op: WatchRemove})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+	return events
+}
@@ -0,0 +1,114 @@
+package finddirs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchConfigDir(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		poll := poll
+		t.Run(map[bool]string{false: "Notify", true: "Poll"}[poll], func(t *testing.T) {
+			root := filepath.ToSlash(t.TempDir())
+			// Directories don't exist yet.
+			d := &AppDirs{ConfigDir: root + "/config/foo"}
+			w, err := d.WatchConfigDir(&WatcherConfig{
+				SearchDirs:   []string{root + "/etc/foo"},
+				Debounce:     20 * time.Millisecond,
+				Poll:         poll,
+				PollInterval: 20 * time.Millisecond,
+			})
+			require.NoError(t, err)
+			defer w.Close()
+			if runtime.GOOS == "linux" {
+				require.Equal(t, poll, w.notifier == nil)
+			}
+
+			require.NoError(t, WriteConfigFile(d, "config.toml", []byte("a = 1")))
+			waitWatchEvent(t, w, d.ConfigDir+"/config.toml", WatchCreate)
+
+			// Written by rename
+			require.NoError(t, WriteConfigFile(d, "config.toml", []byte("a = 22")))
+			waitWatchEvent(t, w, d.ConfigDir+"/config.toml", WatchModify)
+
+			require.NoError(t, WriteConfigFile(d, "themes/dark.json", []byte("{}")))
+			waitWatchEvent(t, w, d.ConfigDir+"/themes/dark.json", WatchCreate)
+
+			require.NoError(t, os.Remove(d.ConfigDir+"/config.toml"))
+			waitWatchEvent(t, w, d.ConfigDir+"/config.toml", WatchRemove)
+
+			require.NoError(t, os.MkdirAll(root+"/etc/foo", 0o755))
+			require.NoError(t, os.WriteFile(root+"/etc/foo/config.toml", nil, 0o644))
+			waitWatchEvent(t, w, root+"/etc/foo/config.toml", WatchCreate)
+
+			require.NoError(t, w.Close())
+			_, ok := <-w.Events
+			require.False(t, ok)
+		})
+	}
+}
+
+// waitWatchEvent waits for the event, and skips other events (e.g. of
+// temporary files seen while polling).
+func waitWatchEvent(t *testing.T, w *Watcher, path string, op WatchOp) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-w.Events:
+			if event.Path == path && event.Op == op {
+				return
+			}
+		case err := <-w.Errors:
+			require.NoError(t, err)
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s of %s", op, path)
+		}
+	}
+}
+
+func TestNotifierSharedWatch(t *testing.T) {
+	n, err := newNotifier()
+	require.NoError(t, err)
+	if n == nil {
+		t.Skip("no notifier on", runtime.GOOS)
+	}
+	defer n.close()
+
+	// Both paths of the directory are reported.
+	dir := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(dir, link))
+	require.NoError(t, n.watch([]string{dir, link}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0o644))
+
+	want := map[string]bool{filepath.Join(dir, "file"): true, filepath.Join(link, "file"): true}
+	timeout := time.After(5 * time.Second)
+	for len(want) > 0 {
+		select {
+		case p := <-n.changes():
+			delete(want, p)
+		case <-timeout:
+			t.Fatalf("timed out waiting for %v", want)
+		}
+	}
+
+	// Watch is kept while one of the paths is watched.
+	require.NoError(t, n.watch([]string{link}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other"), nil, 0o644))
+	for {
+		select {
+		case p := <-n.changes():
+			if p == filepath.Join(link, "other") {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for", filepath.Join(link, "other"))
+		}
+	}
+}
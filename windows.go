@@ -1,56 +1,146 @@
-//go:build windows
-
 package finddirs
 
 import (
 	"path"
-	"path/filepath"
+	"strings"
+)
 
-	"golang.org/x/sys/windows"
+// windowsPlatform retrieves directories as Windows lays them out.
+type windowsPlatform struct{ *lookup }
+
+// KnownFolder is a Windows known folder (see KNOWNFOLDERID) this package
+// uses. `KnownFolderEnv` retrieves their paths.
+type KnownFolder int
+
+const (
+	KnownFolderDesktop KnownFolder = iota
+	KnownFolderDownloads
+	KnownFolderDocuments
+	KnownFolderPictures
+	KnownFolderVideos
+	KnownFolderMusic
+	KnownFolderFonts
+	KnownFolderTemplates
+	KnownFolderPublic
+	KnownFolderRoamingAppData
+	KnownFolderLocalAppData
+	KnownFolderProgramData
+	KnownFolderProgramFiles
+	KnownFolderUserProgramFiles
 )
 
-func knownFolderPath(id *windows.KNOWNFOLDERID) (path string, err error) {
-	flags := []uint32{windows.KF_FLAG_DEFAULT, windows.KF_FLAG_DEFAULT_PATH}
-	for _, flag := range flags {
-		path, err = windows.KnownFolderPath(id, flag|windows.KF_FLAG_DONT_VERIFY)
-		if err == nil {
-			path = filepath.Clean(path)
-			return
+// windowsPath converts backslashes in `p` to slashes, since `p` might
+// not be a path of the system we're running on.
+func windowsPath(p string) string {
+	return path.Clean(strings.ReplaceAll(p, `\`, "/"))
+}
+
+func (l windowsPlatform) knownFolderPath(folder KnownFolder) (string, error) {
+	if env, ok := baseEnv(l.env).(KnownFolderEnv); ok {
+		dir, err := env.KnownFolderPath(folder)
+		if err != nil {
+			return "", err
+		}
+		return windowsPath(dir), nil
+	}
+
+	switch folder {
+	case KnownFolderFonts:
+		return path.Join(l.envPath("SystemRoot", `C:\Windows`), "Fonts"), nil
+	case KnownFolderPublic:
+		return l.envPath("PUBLIC", `C:\Users\Public`), nil
+	case KnownFolderProgramData:
+		return l.envPath("ProgramData", `C:\ProgramData`), nil
+	case KnownFolderProgramFiles:
+		return l.envPath("ProgramFiles", `C:\Program Files`), nil
+	}
+
+	profile := l.env.Getenv("USERPROFILE")
+	if profile == "" {
+		var err error
+		profile, err = l.env.HomeDir()
+		if err != nil {
+			return "", err
 		}
 	}
-	return
+	profile = windowsPath(profile)
+
+	switch folder {
+	case KnownFolderDesktop:
+		return path.Join(profile, "Desktop"), nil
+	case KnownFolderDownloads:
+		return path.Join(profile, "Downloads"), nil
+	case KnownFolderDocuments:
+		return path.Join(profile, "Documents"), nil
+	case KnownFolderPictures:
+		return path.Join(profile, "Pictures"), nil
+	case KnownFolderVideos:
+		return path.Join(profile, "Videos"), nil
+	case KnownFolderMusic:
+		return path.Join(profile, "Music"), nil
+	case KnownFolderTemplates:
+		appData, err := l.knownFolderPath(KnownFolderRoamingAppData)
+		if err != nil {
+			return "", err
+		}
+		return path.Join(appData, "Microsoft/Windows/Templates"), nil
+	case KnownFolderRoamingAppData:
+		return l.envPath("APPDATA", path.Join(profile, "AppData/Roaming")), nil
+	case KnownFolderLocalAppData:
+		return l.envPath("LOCALAPPDATA", path.Join(profile, "AppData/Local")), nil
+	case KnownFolderUserProgramFiles:
+		localAppData, err := l.knownFolderPath(KnownFolderLocalAppData)
+		if err != nil {
+			return "", err
+		}
+		return path.Join(localAppData, "Programs"), nil
+	}
+	panic("finddirs: unknown known folder")
 }
 
-func desktopDir() (string, error) {
-	return knownFolderPath(windows.FOLDERID_Desktop)
+// envPath returns the value of the environment variable `name`,
+// or `fallback` if it is unset.
+func (l windowsPlatform) envPath(name, fallback string) string {
+	if dir := l.env.Getenv(name); dir != "" {
+		return windowsPath(dir)
+	}
+	return windowsPath(fallback)
 }
 
-func downloadsDir() (string, error) {
-	return knownFolderPath(windows.FOLDERID_Downloads)
+func (l windowsPlatform) setUserDirs(dirs map[string]string) error {
+	return ErrOSNotSupportedSetUserDirs
 }
 
-func documentsDir() (string, error) {
-	return knownFolderPath(windows.FOLDERID_Documents)
+func (l windowsPlatform) desktopDir() (string, error) {
+	return l.knownFolderPath(KnownFolderDesktop)
 }
 
-func picturesDir() (string, error) {
-	return knownFolderPath(windows.FOLDERID_Pictures)
+func (l windowsPlatform) downloadsDir() (string, error) {
+	return l.knownFolderPath(KnownFolderDownloads)
 }
 
-func videosDir() (string, error) {
-	return knownFolderPath(windows.FOLDERID_Videos)
+func (l windowsPlatform) documentsDir() (string, error) {
+	return l.knownFolderPath(KnownFolderDocuments)
 }
 
-func musicDir() (string, error) {
-	return knownFolderPath(windows.FOLDERID_Music)
+func (l windowsPlatform) picturesDir() (string, error) {
+	return l.knownFolderPath(KnownFolderPictures)
 }
 
-func fontsDirs() (dirs []string, err error) {
-	dir, err := knownFolderPath(windows.FOLDERID_Fonts)
+func (l windowsPlatform) videosDir() (string, error) {
+	return l.knownFolderPath(KnownFolderVideos)
+}
+
+func (l windowsPlatform) musicDir() (string, error) {
+	return l.knownFolderPath(KnownFolderMusic)
+}
+
+func (l windowsPlatform) fontsDirs() (dirs []string, err error) {
+	dir, err := l.knownFolderPath(KnownFolderFonts)
 	if err != nil {
 		return nil, err
 	}
-	localAppData, err := knownFolderPath(windows.FOLDERID_LocalAppData)
+	localAppData, err := l.knownFolderPath(KnownFolderLocalAppData)
 	if err != nil {
 		return nil, err
 	}
@@ -60,35 +150,78 @@ func fontsDirs() (dirs []string, err error) {
 	}, nil
 }
 
-func templatesDir() (string, error) {
-	return knownFolderPath(windows.FOLDERID_Templates)
+func (l windowsPlatform) templatesDir() (string, error) {
+	return l.knownFolderPath(KnownFolderTemplates)
 }
 
-func publicShareDir() (string, error) {
-	return knownFolderPath(windows.FOLDERID_Public)
+func (l windowsPlatform) publicShareDir() (string, error) {
+	return l.knownFolderPath(KnownFolderPublic)
 }
 
-func (c *AppConfig) subdirPlatformSpecific() string { return c.SubdirWindows }
+func (l windowsPlatform) subdirPlatformSpecific() string { return l.config.SubdirWindows }
+
+// Environment variables that are validated by `ValidateXDGEnv`.
+func (l windowsPlatform) xdgEnvVars() []string { return nil }
 
-func (c *AppConfig) configDirSystem() (string, error) { return programData() }
+func (l windowsPlatform) configDirSystem() (string, error) { return l.programData() }
 
-func (c *AppConfig) configDirLocal() (string, error) { return appData(c.UseRoaming) }
+func (l windowsPlatform) configDirLocal() (string, error) { return l.appData(l.config.UseRoaming) }
 
-func (c *AppConfig) stateDirSystem() (string, error) { return programData() }
+func (l windowsPlatform) stateDirSystem() (string, error) { return l.programData() }
 
-func (c *AppConfig) stateDirLocal() (string, error) { return appData(false) }
+func (l windowsPlatform) stateDirLocal() (string, error) { return l.appData(false) }
 
-func (c *AppConfig) cacheDirSystem() (string, error) { return programData() }
+func (l windowsPlatform) cacheDirSystem() (string, error) { return l.programData() }
 
-func (c *AppConfig) cacheDirLocal() (string, error) { return appData(false) }
+func (l windowsPlatform) cacheDirLocal() (string, error) { return l.appData(false) }
 
-func programData() (string, error) {
-	return knownFolderPath(windows.FOLDERID_ProgramData)
+func (l windowsPlatform) dataDirSystem() (string, error) { return l.programData() }
+
+func (l windowsPlatform) dataDirLocal() (string, error) { return l.appData(false) }
+
+func (l windowsPlatform) runtimeDirSystem() (string, error) { return l.programData() }
+
+// Temporary directory is per-user on Windows.
+func (l windowsPlatform) runtimeDirLocal() (string, error) {
+	for _, name := range []string{"TMP", "TEMP"} {
+		if dir := l.env.Getenv(name); dir != "" {
+			return windowsPath(dir), nil
+		}
+	}
+	localAppData, err := l.knownFolderPath(KnownFolderLocalAppData)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(localAppData, "Temp"), nil
+}
+
+func (l windowsPlatform) programData() (string, error) {
+	return l.knownFolderPath(KnownFolderProgramData)
 }
 
-func appData(roaming bool) (string, error) {
+func (l windowsPlatform) appData(roaming bool) (string, error) {
 	if roaming {
-		return knownFolderPath(windows.FOLDERID_RoamingAppData)
+		return l.knownFolderPath(KnownFolderRoamingAppData)
 	}
-	return knownFolderPath(windows.FOLDERID_LocalAppData)
+	return l.knownFolderPath(KnownFolderLocalAppData)
+}
+
+// System-wide search directories other than `configDirSystem`.
+func (l windowsPlatform) configSearchDirsSystem() ([]string, error) { return nil, nil }
+
+// System-wide search directories other than `dataDirSystem`.
+func (l windowsPlatform) dataSearchDirsSystem() ([]string, error) { return nil, nil }
+
+func (l windowsPlatform) pathEnvVar() string { return "PATH" }
+
+func (l windowsPlatform) pathListSeparator() byte { return ';' }
+
+func (l windowsPlatform) caseInsensitivePaths() bool { return true }
+
+func (l windowsPlatform) binDirSystem() (string, error) {
+	return l.knownFolderPath(KnownFolderProgramFiles)
+}
+
+func (l windowsPlatform) binDirLocal() (string, error) {
+	return l.knownFolderPath(KnownFolderUserProgramFiles)
 }
@@ -4,6 +4,7 @@ package finddirs
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mitchellh/go-homedir"
@@ -16,6 +17,7 @@ func TestWindowsAppDirsSystem(t *testing.T) {
 		UseRoaming:  false,
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(true, config)
 	require.NoError(t, err)
@@ -23,6 +25,7 @@ func TestWindowsAppDirsSystem(t *testing.T) {
 	require.Equal(t, "C:/ProgramData/foo/bar", d.ConfigDir)
 	require.Equal(t, "C:/ProgramData/foo/bar/state", d.StateDir)
 	require.Equal(t, "C:/ProgramData/foo/bar/cache", d.CacheDir)
+	require.Equal(t, "C:/ProgramData/foo/bar/data", d.DataDir)
 }
 
 func TestWindowsAppDirsLocal(t *testing.T) {
@@ -31,6 +34,7 @@ func TestWindowsAppDirsLocal(t *testing.T) {
 		UseRoaming:  false,
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -41,6 +45,7 @@ func TestWindowsAppDirsLocal(t *testing.T) {
 	require.Equal(t, home+"/AppData/Local/foo/bar", d.ConfigDir)
 	require.Equal(t, home+"/AppData/Local/foo/bar/state", d.StateDir)
 	require.Equal(t, home+"/AppData/Local/foo/bar/cache", d.CacheDir)
+	require.Equal(t, home+"/AppData/Local/foo/bar/data", d.DataDir)
 }
 
 func TestWindowsAppDirsSubdirWindows(t *testing.T) {
@@ -50,6 +55,7 @@ func TestWindowsAppDirsSubdirWindows(t *testing.T) {
 		UseRoaming:    false,
 		SubdirState:   "state",
 		SubdirCache:   "cache",
+		SubdirData:    "data",
 	}
 	d, err := RetrieveAppDirs(true, config)
 	require.NoError(t, err)
@@ -57,6 +63,7 @@ func TestWindowsAppDirsSubdirWindows(t *testing.T) {
 	require.Equal(t, "C:/ProgramData/zoo/zar", d.ConfigDir)
 	require.Equal(t, "C:/ProgramData/zoo/zar/state", d.StateDir)
 	require.Equal(t, "C:/ProgramData/zoo/zar/cache", d.CacheDir)
+	require.Equal(t, "C:/ProgramData/zoo/zar/data", d.DataDir)
 
 	d, err = RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -67,6 +74,7 @@ func TestWindowsAppDirsSubdirWindows(t *testing.T) {
 	require.Equal(t, home+"/AppData/Local/zoo/zar", d.ConfigDir)
 	require.Equal(t, home+"/AppData/Local/zoo/zar/state", d.StateDir)
 	require.Equal(t, home+"/AppData/Local/zoo/zar/cache", d.CacheDir)
+	require.Equal(t, home+"/AppData/Local/zoo/zar/data", d.DataDir)
 }
 
 func TestWindowsAppDirsLocalRoaming(t *testing.T) {
@@ -75,6 +83,7 @@ func TestWindowsAppDirsLocalRoaming(t *testing.T) {
 		UseRoaming:  true,
 		SubdirState: "state",
 		SubdirCache: "cache",
+		SubdirData:  "data",
 	}
 	d, err := RetrieveAppDirs(false, config)
 	require.NoError(t, err)
@@ -85,6 +94,7 @@ func TestWindowsAppDirsLocalRoaming(t *testing.T) {
 	require.Equal(t, home+"/AppData/Roaming/foo/bar", d.ConfigDir)
 	require.Equal(t, home+"/AppData/Local/foo/bar/state", d.StateDir)
 	require.Equal(t, home+"/AppData/Local/foo/bar/cache", d.CacheDir)
+	require.Equal(t, home+"/AppData/Local/foo/bar/data", d.DataDir)
 }
 
 func TestWindowsUserDirs(t *testing.T) {
@@ -126,3 +136,33 @@ func TestWindowsUserDirs(t *testing.T) {
 		}
 	}
 }
+
+func TestWindowsSearchDirs(t *testing.T) {
+	config := &AppConfig{Subdir: "foo/bar"}
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+	home = filepath.ToSlash(home)
+
+	dirs, err := RetrieveConfigSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/AppData/Local/foo/bar", "C:/ProgramData/foo/bar"}, dirs)
+	dirs, err = RetrieveDataSearchDirs(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{home + "/AppData/Local/foo/bar", "C:/ProgramData/foo/bar"}, dirs)
+}
+
+func TestWindowsBinDir(t *testing.T) {
+	dir, err := RetrieveBinDir(true)
+	require.NoError(t, err)
+	require.Equal(t, "C:/Program Files", dir)
+
+	home, err := homedir.Dir()
+	require.NoError(t, err)
+	home = filepath.ToSlash(home)
+	dir, err = RetrieveBinDir(false)
+	require.NoError(t, err)
+	require.Equal(t, home+"/AppData/Local/Programs", dir)
+
+	t.Setenv("PATH", `C:\Windows;`+strings.ToUpper(filepath.FromSlash(dir)))
+	require.True(t, IsInPath(dir))
+}
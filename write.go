@@ -0,0 +1,218 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type WriteConfig struct {
+	// Number of backups to keep. Previous contents of the file are kept as
+	// <name>.1 (the most recent one), <name>.2, and so on. If 0, no backups
+	// are kept.
+	Backups int
+
+	// Mode of the file. If 0, 0600 is used, or 0644 for system-wide
+	// config files.
+	Perm fs.FileMode
+
+	// If true, and the file exists, its mode is kept instead of `Perm`.
+	PreserveMode bool
+
+	// If true, symbolic links that point outside the directory (e.g. ones
+	// created by a dotfile manager) are followed too. Otherwise they are
+	// rejected with `ErrInvalidFileName`.
+	FollowSymlinks bool
+}
+
+// WriteConfigFile writes `data` to the file `name` inside `ConfigDir`
+// atomically: data is written to a temporary file inside the same directory
+// and synced, and the temporary file is renamed to `name`. Either the old
+// or the new contents are seen, even if the process crashes.
+//
+// `name` may contain slashes (e.g. "themes/dark.json"). Directories that don't
+// exist are created. If `name` is not a relative path inside the directory,
+// `ErrInvalidFileName` is returned.
+//
+// If the file is a symbolic link, the file it points to is replaced instead
+// of the link, and backups are kept next to that file. Symbolic links (of
+// the file or the directories in `name`) must point inside the directory,
+// otherwise `ErrInvalidFileName` is returned, unless `FollowSymlinks` is set.
+func WriteConfigFile(dirs *AppDirs, name string, data []byte) error {
+	return WriteConfigFileWithConfig(dirs, name, data, nil)
+}
+
+// Same as `WriteConfigFile`, but with a config. `config` can be nil.
+func WriteConfigFileWithConfig(dirs *AppDirs, name string, data []byte, config *WriteConfig) error {
+	return writeAppFile(dirs, KindConfig, name, data, config)
+}
+
+// WriteStateFile is the same as `WriteConfigFile`, but it writes into `StateDir`.
+func WriteStateFile(dirs *AppDirs, name string, data []byte) error {
+	return WriteStateFileWithConfig(dirs, name, data, nil)
+}
+
+// Same as `WriteStateFile`, but with a config. `config` can be nil.
+func WriteStateFileWithConfig(dirs *AppDirs, name string, data []byte, config *WriteConfig) error {
+	return writeAppFile(dirs, KindState, name, data, config)
+}
+
+func writeAppFile(dirs *AppDirs, kind DirKind, name string, data []byte, config *WriteConfig) error {
+	if config == nil {
+		config = new(WriteConfig)
+	}
+	err := checkFileName(name)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	dir := dirs.Dir(kind)
+	if dir == "" {
+		return fmt.Errorf("finddirs: %s directory is not set", kind)
+	}
+
+	dirMode := defaultDirMode(kind, dirs.SystemWide)
+	_, err = ensureDir(dir, dirMode)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	filePath := filepath.FromSlash(path.Join(dir, filepath.ToSlash(name)))
+	if !config.FollowSymlinks {
+		err = checkInsideDir(dir, filepath.Dir(filePath), name)
+		if err != nil {
+			return fmt.Errorf("finddirs: %w", err)
+		}
+	}
+	err = os.MkdirAll(filepath.Dir(filePath), dirMode)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+
+	// Replacing a symbolic link (e.g. one created by a dotfile manager)
+	// would leave the file it points to unchanged.
+	target, err := resolveSymlink(filePath)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	if target != filePath && !config.FollowSymlinks {
+		err = checkInsideDir(dir, filepath.Dir(target), name)
+		if err != nil {
+			return fmt.Errorf("finddirs: %w", err)
+		}
+	}
+	filePath = target
+
+	perm := config.Perm
+	if perm == 0 {
+		perm = dirMode &^ 0o111
+	}
+	fi, err := os.Stat(filePath)
+	if err == nil {
+		if config.PreserveMode {
+			perm = fi.Mode().Perm()
+		}
+		if config.Backups > 0 {
+			err = backupFile(filePath, config.Backups, fi.Mode().Perm())
+		}
+	} else if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+
+	err = writeFileAtomic(filePath, data, perm)
+	if err != nil {
+		return fmt.Errorf("finddirs: %w", err)
+	}
+	return nil
+}
+
+// resolveSymlink returns the file `filePath` points to if it is a symbolic
+// link. Symbolic links to files that don't exist are resolved too, so that
+// the file is created where the link points to.
+func resolveSymlink(filePath string) (string, error) {
+	// Same limit as Linux.
+	for i := 0; i < 40; i++ {
+		fi, err := os.Lstat(filePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return filePath, nil
+		} else if err != nil {
+			return "", err
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			return filePath, nil
+		}
+		target, err := os.Readlink(filePath)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(filePath), target)
+		}
+		filePath = target
+	}
+	return "", fmt.Errorf("too many levels of symbolic links: %s", filePath)
+}
+
+// checkInsideDir returns `ErrInvalidFileName` if `subdir` is not inside
+// `dir` once symbolic links are resolved. Components of `subdir` that
+// don't exist are not resolved.
+func checkInsideDir(dir, subdir, name string) error {
+	realDir, err := filepath.EvalSymlinks(filepath.FromSlash(dir))
+	if err != nil {
+		return err
+	}
+	existing := subdir
+	var missing []string
+	for {
+		_, err := os.Lstat(existing)
+		if err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+	realSubdir, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(realDir, filepath.Join(append([]string{realSubdir}, missing...)...))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %q is a symbolic link to outside of the directory", ErrInvalidFileName, name)
+	}
+	return nil
+}
+
+// backupFile rotates the backups of `filePath`, and keeps its current
+// contents as <filePath>.1. `filePath` itself is left in place, so that it
+// exists until it is replaced.
+func backupFile(filePath string, backups int, perm fs.FileMode) error {
+	backupPath := func(i int) string { return fmt.Sprintf("%s.%d", filePath, i) }
+	for i := backups - 1; i >= 1; i-- {
+		err := os.Rename(backupPath(i), backupPath(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	err := os.Remove(backupPath(1))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	// A hard link is cheaper than a copy, and the file is replaced by
+	// rename, so the contents of the link stay the same.
+	if os.Link(filePath, backupPath(1)) == nil {
+		return nil
+	}
+	return copyFile(filePath, backupPath(1), perm)
+}
@@ -0,0 +1,104 @@
+package finddirs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteConfigFile(t *testing.T) {
+	root := filepath.ToSlash(t.TempDir())
+	d := &AppDirs{ConfigDir: root + "/config/foo", StateDir: root + "/state/foo"}
+
+	require.NoError(t, WriteConfigFile(d, "themes/dark.json", []byte("{}")))
+	data, err := os.ReadFile(d.ConfigDir + "/themes/dark.json")
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(data))
+
+	require.NoError(t, WriteStateFile(d, "history", []byte("ls")))
+	require.FileExists(t, d.StateDir+"/history")
+
+	for _, name := range []string{"", "../escape", "/etc/passwd"} {
+		err = WriteConfigFile(d, name, nil)
+		require.ErrorIs(t, err, ErrInvalidFileName, name)
+	}
+
+	// Backups
+	config := &WriteConfig{Backups: 2}
+	for _, contents := range []string{"1", "2", "3", "4"} {
+		require.NoError(t, WriteConfigFileWithConfig(d, "config.toml", []byte(contents), config))
+	}
+	for name, contents := range map[string]string{
+		"config.toml":   "4",
+		"config.toml.1": "3",
+		"config.toml.2": "2",
+	} {
+		data, err := os.ReadFile(d.ConfigDir + "/" + name)
+		require.NoError(t, err)
+		require.Equal(t, contents, string(data), name)
+	}
+	require.NoFileExists(t, d.ConfigDir+"/config.toml.3")
+
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" {
+		fi, err := os.Stat(d.ConfigDir + "/config.toml")
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+		// Mode is preserved
+		require.NoError(t, os.Chmod(d.ConfigDir+"/config.toml", 0o640))
+		require.NoError(t, WriteConfigFileWithConfig(d, "config.toml", nil, &WriteConfig{PreserveMode: true}))
+		fi, err = os.Stat(d.ConfigDir + "/config.toml")
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+	}
+}
+
+func TestWriteConfigFileSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("symbolic links are not supported")
+	}
+	root := filepath.ToSlash(t.TempDir())
+	d := &AppDirs{ConfigDir: root + "/config/foo"}
+	dotfiles := root + "/dotfiles"
+	require.NoError(t, os.MkdirAll(dotfiles, 0o700))
+	require.NoError(t, os.MkdirAll(d.ConfigDir, 0o700))
+	require.NoError(t, os.WriteFile(dotfiles+"/config.toml", []byte("1"), 0o600))
+	require.NoError(t, os.Symlink("../../dotfiles/config.toml", d.ConfigDir+"/config.toml"))
+
+	// Links to outside of the directory are only followed if asked.
+	err := WriteConfigFile(d, "config.toml", []byte("2"))
+	require.ErrorIs(t, err, ErrInvalidFileName)
+	require.NoError(t, WriteConfigFileWithConfig(d, "config.toml", []byte("2"), &WriteConfig{Backups: 1, FollowSymlinks: true}))
+	fi, err := os.Lstat(d.ConfigDir + "/config.toml")
+	require.NoError(t, err)
+	require.NotZero(t, fi.Mode()&os.ModeSymlink)
+	data, err := os.ReadFile(dotfiles + "/config.toml")
+	require.NoError(t, err)
+	require.Equal(t, "2", string(data))
+	require.FileExists(t, dotfiles+"/config.toml.1")
+
+	// Dangling symbolic links are resolved too.
+	require.NoError(t, os.Symlink(dotfiles+"/new.toml", d.ConfigDir+"/new.toml"))
+	require.ErrorIs(t, WriteConfigFile(d, "new.toml", []byte("new")), ErrInvalidFileName)
+	require.NoError(t, WriteConfigFileWithConfig(d, "new.toml", []byte("new"), &WriteConfig{FollowSymlinks: true}))
+	data, err = os.ReadFile(dotfiles + "/new.toml")
+	require.NoError(t, err)
+	require.Equal(t, "new", string(data))
+
+	// So are directories.
+	require.NoError(t, os.Symlink(dotfiles, d.ConfigDir+"/themes"))
+	require.ErrorIs(t, WriteConfigFile(d, "themes/dark/theme.json", nil), ErrInvalidFileName)
+	require.NoDirExists(t, dotfiles+"/dark")
+
+	// Links inside the directory are followed.
+	require.NoError(t, os.MkdirAll(d.ConfigDir+"/profiles/default", 0o700))
+	require.NoError(t, os.Symlink("profiles/default", d.ConfigDir+"/current"))
+	require.NoError(t, os.Symlink("settings.json", d.ConfigDir+"/profiles/default/link.json"))
+	require.NoError(t, WriteConfigFile(d, "current/link.json", []byte("{}")))
+	data, err = os.ReadFile(d.ConfigDir + "/profiles/default/settings.json")
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(data))
+}
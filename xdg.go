@@ -0,0 +1,432 @@
+package finddirs
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Name of the file xdg-user-dirs keeps locations of user directories in.
+// It resides inside $XDG_CONFIG_HOME (or ~/.config if it is unset).
+const userDirsFileName = "user-dirs.dirs"
+
+// Names of the files xdg-user-dirs-update reads its configuration (from
+// $XDG_CONFIG_HOME and $XDG_CONFIG_DIRS) and default directories (from
+// $XDG_CONFIG_DIRS) from.
+const (
+	userDirsConfFileName     = "user-dirs.conf"
+	userDirsDefaultsFileName = "user-dirs.defaults"
+)
+
+// InvalidEnvError describes why the value of an XDG environment variable
+// (e.g. $XDG_CONFIG_HOME) was rejected.
+type InvalidEnvError struct {
+	// Name of the environment variable.
+	Name string
+	// Value of the environment variable.
+	Value string
+	// Why the value was rejected.
+	Reason string
+}
+
+func (e *InvalidEnvError) Error() string {
+	return fmt.Sprintf("invalid value of $%s (%q): %s", e.Name, e.Value, e.Reason)
+}
+
+// ValidateXDGEnv validates XDG environment variables that are used on this
+// system, and returns the ones that are rejected (and will be ignored unless
+// `StrictEnv` is set) with reasons. `config` can be nil.
+//
+// As XDG Base Directory Specification requires, relative paths are rejected.
+// Paths starting with `~` are rejected too, unless `ExpandTilde` is set.
+func ValidateXDGEnv(config *AppConfig) (errs []*InvalidEnvError, err error) {
+	return defaultResolver.ValidateXDGEnv(config)
+}
+
+// ValidateXDGEnv is the same as the package-level `ValidateXDGEnv`, but it
+// uses the environment of the resolver.
+func (r *Resolver) ValidateXDGEnv(config *AppConfig) (errs []*InvalidEnvError, err error) {
+	l := r.newLookup(config, nil)
+	for _, name := range l.xdgEnvVars() {
+		_, invalid, err := envDir(name, l.env.Getenv(name), l.config.ExpandTilde, l.env.HomeDir)
+		if err != nil {
+			return nil, fmt.Errorf("finddirs: %w", err)
+		}
+		if invalid != nil {
+			errs = append(errs, invalid)
+		}
+	}
+	return
+}
+
+// envDir validates and normalizes `value` of the XDG environment variable
+// `name`. If the value is empty or rejected, empty string is returned. In the
+// latter case, `invalid` is non-nil and describes why it was rejected.
+//
+// If `expandTilde` is true, a leading `~` is expanded to the home directory
+// instead of being rejected. `home` is only called in that case.
+func envDir(name, value string, expandTilde bool, home func() (string, error)) (dir string, invalid *InvalidEnvError, err error) {
+	if value == "" {
+		return "", nil, nil
+	}
+	reject := func(reason string) (string, *InvalidEnvError, error) {
+		return "", &InvalidEnvError{Name: name, Value: value, Reason: reason}, nil
+	}
+
+	dir = filepath.ToSlash(value)
+	if dir[0] == '~' {
+		if !expandTilde {
+			return reject("path starts with ~, which is not expanded")
+		}
+		if dir != "~" && dir[1] != '/' {
+			return reject("~user form is not supported")
+		}
+		h, err := home()
+		if err != nil {
+			return "", nil, err
+		}
+		dir = filepath.ToSlash(h) + dir[1:]
+	}
+	if !path.IsAbs(dir) && !filepath.IsAbs(dir) {
+		return reject("path is relative")
+	}
+	return path.Clean(dir), nil, nil
+}
+
+// ParseError is returned when user-dirs.dirs is malformed.
+type ParseError struct {
+	// Path of the file that couldn't be parsed.
+	Path string
+	// Line number the error occurred at. Starts from 1.
+	Line int
+	// Description of the error.
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	name := e.Path
+	if name == "" {
+		name = userDirsFileName
+	}
+	return fmt.Sprintf("%s:%d: %s", name, e.Line, e.Msg)
+}
+
+// parseUserDirs parses the contents of user-dirs.dirs without executing it.
+//
+// user-dirs.dirs is meant to be sourced by a shell, but in practice it only
+// consists of `XDG_<KEY>_DIR="$HOME/<dir>"` assignments. Only a small subset
+// of shell syntax is supported: comments, optional `export` keyword,
+// single and double quotes, backslash escapes, and expansion of `$HOME`
+// (or `${HOME}`). Anything else results with a `*ParseError`.
+//
+// Returned map is keyed by the name of the directory (e.g. "DOWNLOAD" for
+// `XDG_DOWNLOAD_DIR`). Assignments to other variables are ignored.
+func parseUserDirs(filePath string, data []byte, home string) (map[string]string, error) {
+	dirs := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		newErr := func(msg string) error {
+			return &ParseError{Path: filePath, Line: i + 1, Msg: msg}
+		}
+
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+		name, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, newErr("expected an assignment in the form of NAME=value")
+		}
+		if !isShellName(name) {
+			return nil, newErr(fmt.Sprintf("invalid variable name: %q", name))
+		}
+		value, err := parseShellWord(rawValue, home)
+		if err != nil {
+			return nil, newErr(err.Error())
+		}
+
+		key, ok := strings.CutPrefix(name, "XDG_")
+		if !ok {
+			continue
+		}
+		key, ok = strings.CutSuffix(key, "_DIR")
+		if !ok || key == "" {
+			continue
+		}
+		if value != "" && !path.IsAbs(value) {
+			return nil, newErr(fmt.Sprintf("%s must be either an absolute path or relative to $HOME", name))
+		}
+		dirs[key] = value
+	}
+	return dirs, nil
+}
+
+// Header of user-dirs.dirs that is written if it doesn't exist.
+const userDirsFileHeader = `# This file is written by xdg-user-dirs-update
+# If you want to change or add directories, just edit the line you're
+# interested in. All local changes will be retained on the next run.
+# Format is XDG_xxx_DIR="$HOME/yyy", where yyy is a shell-escaped
+# homedir-relative path, or XDG_xxx_DIR="/yyy", where /yyy is an
+# absolute path. No other format is supported.
+#
+`
+
+// updateUserDirs sets the entries of user-dirs.dirs (`data`) to `dirs`.
+// Lines of existing entries are replaced, comments and other lines are
+// kept as is. Entries that don't exist are appended at the end.
+func updateUserDirs(data []byte, dirs map[string]string, home string) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		lines[len(lines)-1] += "\n"
+	}
+
+	written := make(map[string]bool)
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+		name, _, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, ok := strings.CutPrefix(name, "XDG_")
+		if !ok {
+			continue
+		}
+		key, ok = strings.CutSuffix(key, "_DIR")
+		if !ok {
+			continue
+		}
+		dir, ok := dirs[key]
+		if !ok {
+			continue
+		}
+		lines[i] = formatUserDir(key, dir, home)
+		written[key] = true
+	}
+
+	// Sort for deterministic output.
+	keys := make([]string, 0, len(dirs))
+	for key := range dirs {
+		if !written[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, formatUserDir(key, dirs[key], home))
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// formatUserDir formats a line of user-dirs.dirs. If `dir` is inside `home`,
+// it is written relative to $HOME.
+func formatUserDir(key, dir, home string) string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+	dir = path.Clean(dir)
+	home = path.Clean(home)
+	var value string
+	switch {
+	case dir == home:
+		value = "$HOME/"
+	case strings.HasPrefix(dir, home+"/") && home != "/":
+		value = "$HOME/" + escape.Replace(strings.TrimPrefix(dir, home+"/"))
+	default:
+		value = escape.Replace(dir)
+	}
+	return fmt.Sprintf("XDG_%s_DIR=\"%s\"\n", key, value)
+}
+
+// parseUserDirsConf parses user-dirs.conf and returns false if
+// xdg-user-dirs is disabled (`enabled=False`).
+func parseUserDirsConf(data []byte) (enabled bool) {
+	enabled = true
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) == "enabled" {
+			enabled = !strings.EqualFold(strings.TrimSpace(value), "false")
+		}
+	}
+	return
+}
+
+// parseUserDirsDefaults parses user-dirs.defaults. Its entries are in the form
+// of `DOWNLOAD=Downloads`, and they are relative to `home`. Malformed lines are
+// ignored, just like xdg-user-dirs-update does.
+func parseUserDirsDefaults(data []byte, home string) map[string]string {
+	dirs := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if !ok || !isShellName(key) || value == "" {
+			continue
+		}
+		if !path.IsAbs(value) {
+			value = path.Join(home, value)
+		}
+		dirs[key] = value
+	}
+	return dirs
+}
+
+// builtinUserDirs returns the directories xdg-user-dirs-update uses
+// if user-dirs.defaults doesn't exist.
+func builtinUserDirs(home string) map[string]string {
+	return map[string]string{
+		"DESKTOP":     path.Join(home, "Desktop"),
+		"DOWNLOAD":    path.Join(home, "Downloads"),
+		"TEMPLATES":   path.Join(home, "Templates"),
+		"PUBLICSHARE": path.Join(home, "Public"),
+		"DOCUMENTS":   path.Join(home, "Documents"),
+		"MUSIC":       path.Join(home, "Music"),
+		"PICTURES":    path.Join(home, "Pictures"),
+		"VIDEOS":      path.Join(home, "Videos"),
+	}
+}
+
+func isShellName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isShellNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isShellNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// parseShellWord parses the right-hand side of a shell assignment, expanding
+// `$HOME` to `home`.
+func parseShellWord(s string, home string) (string, error) {
+	var b strings.Builder
+
+	expand := func(i int) (int, error) {
+		switch {
+		case strings.HasPrefix(s[i:], "${HOME}"):
+			b.WriteString(home)
+			return i + len("${HOME}"), nil
+		case strings.HasPrefix(s[i:], "$HOME"):
+			end := i + len("$HOME")
+			if end < len(s) && isShellNameChar(s[end]) {
+				break
+			}
+			b.WriteString(home)
+			return end, nil
+		}
+		return 0, errors.New("only $HOME can be expanded")
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '"':
+			i++
+			closed := false
+			for i < len(s) && !closed {
+				switch s[i] {
+				case '"':
+					closed = true
+					i++
+				case '\\':
+					if i+1 < len(s) && strings.IndexByte("$`\"\\", s[i+1]) != -1 {
+						b.WriteByte(s[i+1])
+						i += 2
+					} else {
+						b.WriteByte('\\')
+						i++
+					}
+				case '$':
+					var err error
+					i, err = expand(i)
+					if err != nil {
+						return "", err
+					}
+				case '`':
+					return "", errors.New("command substitution is not supported")
+				default:
+					b.WriteByte(s[i])
+					i++
+				}
+			}
+			if !closed {
+				return "", errors.New("unterminated double quote")
+			}
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end == -1 {
+				return "", errors.New("unterminated single quote")
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 2
+		case '\\':
+			if i+1 >= len(s) {
+				return "", errors.New("trailing backslash")
+			}
+			b.WriteByte(s[i+1])
+			i += 2
+		case '$':
+			var err error
+			i, err = expand(i)
+			if err != nil {
+				return "", err
+			}
+		case ' ', '\t':
+			rest := strings.TrimSpace(s[i:])
+			if rest != "" && rest[0] != '#' {
+				return "", fmt.Errorf("unexpected text after value: %q", rest)
+			}
+			return b.String(), nil
+		case ';', '&', '|', '<', '>', '(', ')', '`':
+			return "", fmt.Errorf("unsupported shell syntax: %q", c)
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// splitXDGDirs splits a colon separated list of directories (e.g. the value of
+// $XDG_CONFIG_DIRS). As XDG Base Directory Specification requires, entries that
+// are empty or relative are ignored. Duplicate entries are removed.
+func splitXDGDirs(value string) (dirs []string) {
+	for _, dir := range strings.Split(value, ":") {
+		if !path.IsAbs(dir) {
+			continue
+		}
+		dirs = appendUnique(dirs, path.Clean(dir))
+	}
+	return
+}
+
+func appendUnique(dirs []string, dir string) []string {
+	for _, d := range dirs {
+		if d == dir {
+			return dirs
+		}
+	}
+	return append(dirs, dir)
+}
@@ -0,0 +1,139 @@
+package finddirs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserDirs(t *testing.T) {
+	data := []byte(`# This file is written by xdg-user-dirs-update
+# If you want to change or add directories, just edit the line you're
+# interested in. All local changes will be retained on the next run.
+XDG_DESKTOP_DIR="$HOME/Desktop"
+XDG_DOWNLOAD_DIR="${HOME}/My \"Downloads\""
+export XDG_DOCUMENTS_DIR='/mnt/docs'
+XDG_MUSIC_DIR=$HOME/Music\ Library # trailing comment
+XDG_TEMPLATES_DIR="$HOME/"
+XDG_PUBLICSHARE_DIR=""
+FOO="bar"
+`)
+	dirs, err := parseUserDirs("", data, "/home/user")
+	require.NoError(t, err)
+	require.Equal(t,
+		map[string]string{
+			"DESKTOP":     "/home/user/Desktop",
+			"DOWNLOAD":    `/home/user/My "Downloads"`,
+			"DOCUMENTS":   "/mnt/docs",
+			"MUSIC":       "/home/user/Music Library",
+			"TEMPLATES":   "/home/user/",
+			"PUBLICSHARE": "",
+		},
+		dirs,
+	)
+}
+
+func TestParseUserDirsMalformed(t *testing.T) {
+	tests := []string{
+		"XDG_DESKTOP_DIR",
+		"XDG DESKTOP DIR=\"$HOME/Desktop\"",
+		"XDG_DESKTOP_DIR=\"$HOME/Desktop",
+		"XDG_DESKTOP_DIR='$HOME/Desktop",
+		"XDG_DESKTOP_DIR=\"$USER/Desktop\"",
+		"XDG_DESKTOP_DIR=\"$(whoami)\"",
+		"XDG_DESKTOP_DIR=\"`whoami`\"",
+		"XDG_DESKTOP_DIR=$HOME/Desktop; rm -rf /",
+		"XDG_DESKTOP_DIR=\"Desktop\"",
+		"XDG_DESKTOP_DIR=\"$HOME/Desktop\" foo",
+	}
+	for _, test := range tests {
+		_, err := parseUserDirs("/home/user/.config/user-dirs.dirs", []byte("# comment\n"+test+"\n"), "/home/user")
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr, test)
+		require.Equal(t, 2, parseErr.Line, test)
+		require.Equal(t, "/home/user/.config/user-dirs.dirs", parseErr.Path, test)
+	}
+}
+
+func TestSplitXDGDirs(t *testing.T) {
+	require.Equal(t,
+		[]string{"/opt/xdg", "/etc/xdg"},
+		splitXDGDirs("/opt/xdg::relative/dir:/etc/xdg:/opt/xdg/:~/.config"),
+	)
+	require.Empty(t, splitXDGDirs(""))
+}
+
+func TestParseUserDirsDefaults(t *testing.T) {
+	data := []byte(`# Default settings for user directories
+DESKTOP=Desktop
+DOWNLOAD = Downloads
+MUSIC=/srv/music
+malformed line
+=Nothing
+`)
+	require.Equal(t,
+		map[string]string{
+			"DESKTOP":  "/home/user/Desktop",
+			"DOWNLOAD": "/home/user/Downloads",
+			"MUSIC":    "/srv/music",
+		},
+		parseUserDirsDefaults(data, "/home/user"),
+	)
+}
+
+func TestParseUserDirsConf(t *testing.T) {
+	require.True(t, parseUserDirsConf([]byte("# enabled=False\nfilename_encoding=UTF-8\n")))
+	require.True(t, parseUserDirsConf([]byte("enabled=True\n")))
+	require.False(t, parseUserDirsConf([]byte("enabled=False\n")))
+}
+
+func TestXDGEnvDir(t *testing.T) {
+	home := func() (string, error) { return "/home/user", nil }
+	tests := []struct {
+		value       string
+		expandTilde bool
+		dir         string
+		invalid     bool
+	}{
+		{"", false, "", false},
+		{"/home/user/.config/", false, "/home/user/.config", false},
+		{".config", false, "", true},
+		{"~/.config", false, "", true},
+		{"~/.config", true, "/home/user/.config", false},
+		{"~", true, "/home/user", false},
+		{"~root/.config", true, "", true},
+	}
+	for _, test := range tests {
+		dir, invalid, err := envDir("XDG_CONFIG_HOME", test.value, test.expandTilde, home)
+		require.NoError(t, err)
+		require.Equal(t, test.dir, dir, test.value)
+		require.Equal(t, test.invalid, invalid != nil, test.value)
+		if invalid != nil {
+			require.Equal(t, "XDG_CONFIG_HOME", invalid.Name)
+			require.Equal(t, test.value, invalid.Value)
+		}
+	}
+}
+
+func TestUpdateUserDirs(t *testing.T) {
+	data := []byte(`# comment
+XDG_DESKTOP_DIR="$HOME/Desktop"
+FOO=bar
+XDG_MUSIC_DIR="$HOME/Music"`)
+	data = updateUserDirs(data, map[string]string{
+		"MUSIC":    "/srv/music",
+		"DOWNLOAD": `/home/user/My "$Downloads"`,
+		"VIDEOS":   "/home/user",
+	}, "/home/user")
+	require.Equal(t, `# comment
+XDG_DESKTOP_DIR="$HOME/Desktop"
+FOO=bar
+XDG_MUSIC_DIR="/srv/music"
+XDG_DOWNLOAD_DIR="$HOME/My \"\$Downloads\""
+XDG_VIDEOS_DIR="$HOME/"
+`, string(data))
+
+	dirs, err := parseUserDirs("", data, "/home/user")
+	require.NoError(t, err)
+	require.Equal(t, `/home/user/My "$Downloads"`, dirs["DOWNLOAD"])
+}